@@ -13,6 +13,14 @@ type (
 	CompletionRequest = sdk.CompletionRequest
 	Tool              = sdk.Tool
 	InputSchema       = sdk.InputSchema
+	Conversation      = sdk.Conversation
+	TokenWindow       = sdk.TokenWindow
+	LastTurns         = sdk.LastTurns
+	Summarizer        = sdk.Summarizer
+	FallbackBackend   = sdk.FallbackBackend
+	PoolKey           = sdk.PoolKey
+	Budget            = sdk.Budget
+	SearchOptions     = sdk.SearchOptions
 )
 
 func Anannas(apiKey string) *SDK {
@@ -50,3 +58,7 @@ func Perplexity(apiKey string) *SDK {
 func Xai(apiKey string) *SDK {
 	return sdk.NewSDK(providers.NewXaiProvider(apiKey))
 }
+
+func Fallback(backends ...FallbackBackend) *SDK {
+	return sdk.NewSDK(sdk.NewFallbackProvider(backends...))
+}
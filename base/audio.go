@@ -0,0 +1,156 @@
+// shared audio endpoints for OpenAI compatible APIs
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// transcribes audio with an OpenAI compatible multipart POST /audio/transcriptions endpoint
+// the audio is streamed to the provider without buffering it
+func (p *Provider) TranscribeOpenAI(ctx context.Context, endpoint string, header http.Header, model string, req *sdk.TranscriptionRequest) (*sdk.Transcription, error) {
+	// checked before the form is written in its own goroutine, where a nil reader would panic
+	if req == nil || req.Audio == nil {
+		return nil, errors.New("transcribing audio: TranscriptionRequest.Audio is not set")
+	}
+	if model == "" {
+		return nil, errors.New("transcribing audio: no model")
+	}
+
+	body, w := io.Pipe()
+	form := multipart.NewWriter(w)
+
+	go func() {
+		w.CloseWithError(writeTranscriptionForm(form, model, req))
+	}()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	for name, values := range header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.Do(httpReq)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Text     string  `json:"text"`
+		Language string  `json:"language"`
+		Duration float64 `json:"duration"`
+		Segments []struct {
+			ID    int     `json:"id"`
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+		Words []struct {
+			Word  string  `json:"word"`
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+		} `json:"words"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &sdk.Transcription{
+		Text:     parsed.Text,
+		Language: parsed.Language,
+		Duration: seconds(parsed.Duration),
+	}
+	for _, s := range parsed.Segments {
+		result.Segments = append(result.Segments, sdk.TranscriptionSegment{ID: s.ID, Start: seconds(s.Start), End: seconds(s.End), Text: s.Text})
+	}
+	for _, w := range parsed.Words {
+		result.Words = append(result.Words, sdk.TranscriptionWord{Word: w.Word, Start: seconds(w.Start), End: seconds(w.End)})
+	}
+	return result, nil
+}
+
+func writeTranscriptionForm(form *multipart.Writer, model string, req *sdk.TranscriptionRequest) error {
+	filename := req.Filename
+	if filename == "" {
+		filename = "audio.mp3"
+	}
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, req.Audio); err != nil {
+		return err
+	}
+
+	fields := [][2]string{{"model", model}}
+	if req.Language != "" {
+		fields = append(fields, [2]string{"language", req.Language})
+	}
+	if req.Prompt != "" {
+		fields = append(fields, [2]string{"prompt", req.Prompt})
+	}
+	if req.Temperature != 0 {
+		fields = append(fields, [2]string{"temperature", strconv.FormatFloat(float64(req.Temperature), 'f', -1, 32)})
+	}
+	if req.Timestamps || req.WordTimestamps {
+		fields = append(fields,
+			[2]string{"response_format", "verbose_json"},
+			[2]string{"timestamp_granularities[]", "segment"},
+		)
+	} else {
+		fields = append(fields, [2]string{"response_format", "json"})
+	}
+	if req.WordTimestamps {
+		fields = append(fields, [2]string{"timestamp_granularities[]", "word"})
+	}
+
+	for _, f := range fields {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return form.Close()
+}
+
+// synthesizes speech with an OpenAI compatible POST /audio/speech endpoint and returns the audio stream
+func (p *Provider) SpeakOpenAI(ctx context.Context, endpoint string, header http.Header, body map[string]interface{}) (io.ReadCloser, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
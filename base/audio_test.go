@@ -0,0 +1,115 @@
+package base
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// provider answering every request with body, and decoding the multipart form it was sent
+func formProvider(t *testing.T, body string, form **multipart.Form) *Provider {
+	return &Provider{HTTPClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		*form, err = multipart.NewReader(req.Body, params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}}
+}
+
+func TestTranscribeForm(t *testing.T) {
+	var form *multipart.Form
+	p := formProvider(t, `{
+		"text": "hello there",
+		"language": "english",
+		"duration": 1.5,
+		"segments": [{"id": 0, "start": 0, "end": 1.5, "text": "hello there"}],
+		"words": [{"word": "hello", "start": 0, "end": 0.5}, {"word": "there", "start": 0.6, "end": 1.5}]
+	}`, &form)
+
+	result, err := p.TranscribeOpenAI(context.Background(), "https://example.com/audio/transcriptions", http.Header{"Authorization": {"Bearer key"}}, "whisper-1", &sdk.TranscriptionRequest{
+		Audio:          strings.NewReader("RIFF audio"),
+		Filename:       "meeting.wav",
+		Language:       "en",
+		WordTimestamps: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := form.Value["response_format"]; !slices.Equal(got, []string{"verbose_json"}) {
+		t.Errorf("response_format = %v", got)
+	}
+	if got := form.Value["timestamp_granularities[]"]; !slices.Equal(got, []string{"segment", "word"}) {
+		t.Errorf("timestamp_granularities[] = %v", got)
+	}
+	if form.Value["model"][0] != "whisper-1" || form.Value["language"][0] != "en" {
+		t.Errorf("fields = %v", form.Value)
+	}
+	file := form.File["file"][0]
+	f, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(f); file.Filename != "meeting.wav" || string(data) != "RIFF audio" {
+		t.Errorf("file %q = %q", file.Filename, data)
+	}
+
+	if result.Text != "hello there" || len(result.Segments) != 1 || len(result.Words) != 2 || result.Words[1].Start.Milliseconds() != 600 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestTranscribePlainJSON(t *testing.T) {
+	var form *multipart.Form
+	p := formProvider(t, `{"text": "hi"}`, &form)
+
+	if _, err := p.TranscribeOpenAI(context.Background(), "https://example.com", nil, "whisper-1", &sdk.TranscriptionRequest{Audio: strings.NewReader("audio")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := form.Value["response_format"]; !slices.Equal(got, []string{"json"}) {
+		t.Errorf("response_format = %v", got)
+	}
+	if _, ok := form.Value["timestamp_granularities[]"]; ok {
+		t.Errorf("timestamp_granularities[] sent without timestamps")
+	}
+	if form.File["file"][0].Filename != "audio.mp3" {
+		t.Errorf("default filename = %q", form.File["file"][0].Filename)
+	}
+}
+
+func TestTranscribeMissingInput(t *testing.T) {
+	var form *multipart.Form
+	p := formProvider(t, `{}`, &form)
+
+	if _, err := p.TranscribeOpenAI(context.Background(), "https://example.com", nil, "whisper-1", &sdk.TranscriptionRequest{}); err == nil {
+		t.Error("transcribed without audio")
+	}
+	if _, err := p.TranscribeOpenAI(context.Background(), "https://example.com", nil, "", &sdk.TranscriptionRequest{Audio: strings.NewReader("audio")}); err == nil {
+		t.Error("transcribed without a model")
+	}
+	if form != nil {
+		t.Error("a request was sent")
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 
 	"github.com/xerohard/ai/v2/sdk"
 )
 
 type Provider struct {
 	APICaller
+	HTTPClient  *http.Client // defaults to http.DefaultClient
+	Logger      *slog.Logger // defaults to the logger attached to the context
+	LogPayloads bool         // logs request and response bodies at debug level
 }
 
 type APICaller interface {
@@ -22,11 +27,23 @@ type StreamParser interface {
 	ParseResponse(body io.Reader, onChunk func(string) error) error
 }
 
+// implemented by providers whose streams carry more than text, e.g. reasoning deltas
+// used instead of StreamParser when implemented, text events are written to the stream
+// and reasoning events too when Options.IncludeReasoning is set
+type EventParser interface {
+	ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error
+}
+
+// implemented by providers whose non-streaming responses are not in the OpenAI format
+type ResponseExtractor interface {
+	ExtractResponse(body []byte) (*sdk.CompletionResponse, error)
+}
+
 // adds a system prompt to the beginning of the messages
 func (p *Provider) AddSystemPrompt(messages []sdk.Message, opts *sdk.Options) []sdk.Message {
 	if opts != nil && opts.SystemPrompt != "" {
 		if len(messages) == 0 || messages[0].Role != "system" {
-			return append([]sdk.Message{{Role: "system", Content: opts.SystemPrompt}}, messages...)
+			return append([]sdk.Message{{Role: "system", Content: opts.SystemPrompt, CacheControl: opts.SystemCacheControl}}, messages...)
 		}
 	}
 	return messages
@@ -51,6 +68,10 @@ func (p *Provider) CreateCompletion(
 		return nil, err
 	}
 
+	if extractor, ok := p.APICaller.(ResponseExtractor); ok {
+		return extractor.ExtractResponse(respBytes)
+	}
+
 	content, err := ExtractJsonResponse(respBytes)
 	if err != nil {
 		return &sdk.CompletionResponse{
@@ -74,8 +95,54 @@ func (p *Provider) CreateCompletionStream(
 		return nil, err
 	}
 
-	parser, ok := p.APICaller.(StreamParser)
-	if !ok {
+	var parse func(onChunk func(string) error) error
+	if parser, ok := p.APICaller.(EventParser); ok {
+		var onEvent func(sdk.StreamEvent)
+		includeReasoning := false
+		if opts != nil {
+			onEvent = opts.OnStreamEvent
+			includeReasoning = opts.IncludeReasoning
+		}
+		parse = func(onChunk func(string) error) error {
+			// reasoning written to the stream is wrapped in <think> tags
+			thinking := false
+			err := parser.ParseEvents(body, func(event sdk.StreamEvent) error {
+				if onEvent != nil {
+					onEvent(event)
+				}
+				switch event.Type {
+				case sdk.StreamEventText:
+					if thinking {
+						thinking = false
+						if err := onChunk("\n</think>\n\n"); err != nil {
+							return err
+						}
+					}
+					return onChunk(event.Text)
+				case sdk.StreamEventReasoning:
+					if !includeReasoning {
+						return nil
+					}
+					if !thinking {
+						thinking = true
+						if err := onChunk("<think>\n"); err != nil {
+							return err
+						}
+					}
+					return onChunk(event.Text)
+				}
+				return nil
+			})
+			if err == nil && thinking {
+				err = onChunk("\n</think>\n")
+			}
+			return err
+		}
+	} else if parser, ok := p.APICaller.(StreamParser); ok {
+		parse = func(onChunk func(string) error) error {
+			return parser.ParseResponse(body, onChunk)
+		}
+	} else {
 		body.Close()
 		return nil, fmt.Errorf("streaming not supported by this provider")
 	}
@@ -84,7 +151,7 @@ func (p *Provider) CreateCompletionStream(
 
 	go func() {
 		defer body.Close()
-		err := parser.ParseResponse(body, func(chunk string) error {
+		err := parse(func(chunk string) error {
 			_, writeErr := w.Write([]byte(chunk))
 			return writeErr
 		})
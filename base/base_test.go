@@ -0,0 +1,72 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// replays an OpenAI style event stream
+type eventCaller struct {
+	body string
+}
+
+func (c *eventCaller) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.body)), nil
+}
+
+func (c *eventCaller) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return ParseJsonEvents(body, onEvent)
+}
+
+// event stream with a delta per field:value pair, e.g. "reasoning:hmm"
+func deltas(pairs ...string) string {
+	var b strings.Builder
+	for _, p := range pairs {
+		field, value, _ := strings.Cut(p, ":")
+		fmt.Fprintf(&b, "data: {\"choices\":[{\"delta\":{%q:%q}}]}\n\n", field, value)
+	}
+	b.WriteString("data: [DONE]\n\n")
+	return b.String()
+}
+
+func TestStreamThinkTags(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		includeReasoning bool
+		want             string
+	}{
+		{"reasoning then text", deltas("reasoning:let me", "reasoning_content: think", "content:answer"), true, "<think>\nlet me think\n</think>\n\nanswer"},
+		{"reasoning left out", deltas("reasoning:let me", "content:answer"), false, "answer"},
+		{"reasoning only", deltas("reasoning:hmm"), true, "<think>\nhmm\n</think>\n"},
+		{"text only", deltas("content:hel", "content:lo"), true, "hello"},
+	}
+	for _, tt := range tests {
+		p := &Provider{APICaller: &eventCaller{body: tt.body}}
+
+		var reasoning int
+		stream, err := p.CreateCompletionStream(context.Background(), nil, &sdk.Options{
+			IncludeReasoning: tt.includeReasoning,
+			OnStreamEvent: func(e sdk.StreamEvent) {
+				if e.Type == sdk.StreamEventReasoning {
+					reasoning++
+				}
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := io.ReadAll(stream)
+		if err != nil || string(text) != tt.want {
+			t.Errorf("%s: stream = %q, %v, want %q", tt.name, text, err, tt.want)
+		}
+		if want := strings.Count(tt.body, `"reasoning`); reasoning != want {
+			t.Errorf("%s: %d reasoning events, want %d", tt.name, reasoning, want)
+		}
+	}
+}
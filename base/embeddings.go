@@ -0,0 +1,74 @@
+// shared embeddings for OpenAI compatible APIs
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// embeds a batch with an OpenAI compatible POST /embeddings endpoint
+// extra is merged into the request body, e.g. for provider specific parameters
+func (p *Provider) EmbedOpenAI(ctx context.Context, endpoint string, header http.Header, req *sdk.EmbedRequest, extra map[string]interface{}) (*sdk.EmbedResponse, error) {
+	body := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+	}
+	if req.Dimensions > 0 {
+		body["dimensions"] = req.Dimensions
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+
+	result := &sdk.EmbedResponse{
+		Model: parsed.Model,
+		Usage: sdk.Usage{InputTokens: parsed.Usage.PromptTokens, TotalTokens: parsed.Usage.TotalTokens},
+	}
+	for _, d := range parsed.Data {
+		result.Embeddings = append(result.Embeddings, d.Embedding)
+	}
+	return result, nil
+}
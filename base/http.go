@@ -0,0 +1,134 @@
+// shared http handling for providers
+
+package base
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// query parameters carrying credentials, never logged
+var secretParams = []string{"key", "api_key"}
+
+// sends a request, reports the response headers and turns non 200 responses into APIError
+func (p *Provider) Do(req *http.Request) (*http.Response, error) {
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx := req.Context()
+	logger := p.logger(req)
+	payloads := p.LogPayloads || sdk.LogPayloads(ctx)
+	target := RedactURL(req.URL)
+
+	attrs := []any{"method", req.Method, "url", target}
+	if payloads && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := io.ReadAll(body)
+			attrs = append(attrs, "body", string(b))
+		}
+	}
+	logger.DebugContext(ctx, "sending request", attrs...)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		// url.Error embeds the full url, including a key query parameter
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = target
+		}
+		logger.ErrorContext(ctx, "request failed", "method", req.Method, "url", target, "latency", time.Since(start), "error", err)
+		return nil, err
+	}
+
+	sdk.ObserveHeaders(ctx, resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		attrs := []any{"method", req.Method, "url", target, "status", resp.StatusCode, "latency", time.Since(start)}
+		if payloads {
+			attrs = append(attrs, "body", string(b))
+		}
+		logger.WarnContext(ctx, "request returned an error status", attrs...)
+
+		return nil, &sdk.APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(b),
+			Body:       b,
+			Header:     resp.Header,
+		}
+	}
+
+	logger.DebugContext(ctx, "received response", "method", req.Method, "url", target, "status", resp.StatusCode, "latency", time.Since(start))
+
+	if payloads {
+		resp.Body = &loggedBody{ReadCloser: resp.Body, onClose: func(b []byte) {
+			logger.DebugContext(ctx, "response body", "url", target, "body", string(b))
+		}}
+	}
+
+	return resp, nil
+}
+
+// the provider logger, falling back to the one attached to the request context
+func (p *Provider) logger(req *http.Request) *slog.Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return sdk.Logger(req.Context())
+}
+
+// returns the url with credential query parameters replaced
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	query := u.Query()
+	redacted := false
+	for _, name := range secretParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = query.Encode()
+	return c.String()
+}
+
+// buffers what is read from a response body and logs it on close
+type loggedBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	onClose func(b []byte)
+	closed  bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.buf.Bytes())
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// shared image generation for OpenAI compatible APIs
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// generates images with an OpenAI compatible POST /images/generations endpoint
+// body holds the request parameters the provider supports
+func (p *Provider) GenerateOpenAIImage(ctx context.Context, endpoint string, header http.Header, model string, body map[string]interface{}) (*sdk.ImageResponse, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Data []struct {
+			URL           string `json:"url"`
+			B64JSON       string `json:"b64_json"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &sdk.ImageResponse{
+		Model: model,
+		Usage: sdk.Usage{
+			InputTokens:  parsed.Usage.InputTokens,
+			OutputTokens: parsed.Usage.OutputTokens,
+			TotalTokens:  parsed.Usage.TotalTokens,
+		},
+	}
+	for _, d := range parsed.Data {
+		img := sdk.Image{URL: d.URL, RevisedPrompt: d.RevisedPrompt}
+		if d.B64JSON != "" {
+			img.Data, err = base64.StdEncoding.DecodeString(d.B64JSON)
+			if err != nil {
+				return nil, err
+			}
+			img.MIMEType = http.DetectContentType(img.Data)
+		}
+		result.Images = append(result.Images, img)
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+// shared model listing for OpenAI compatible APIs
+
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// lists models from an OpenAI compatible GET /models endpoint
+// understands the context length and pricing extensions of Groq, Mistral and OpenRouter,
+// and follows has_more/last_id pagination
+func (p *Provider) ListOpenAIModels(ctx context.Context, endpoint, provider string, header http.Header) ([]sdk.ModelInfo, error) {
+	var models []sdk.ModelInfo
+	after := ""
+	for {
+		target := endpoint
+		if after != "" {
+			target += "?after=" + url.QueryEscape(after)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := p.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Data []struct {
+				ID               string `json:"id"`
+				Name             string `json:"name"`
+				OwnedBy          string `json:"owned_by"`
+				Created          int64  `json:"created"`
+				ContextWindow    int    `json:"context_window"`     // groq
+				MaxContextLength int    `json:"max_context_length"` // mistral
+				ContextLength    int    `json:"context_length"`     // openrouter
+				TopProvider      struct {
+					MaxCompletionTokens int `json:"max_completion_tokens"`
+				} `json:"top_provider"`
+				Pricing *struct {
+					Prompt            string `json:"prompt"`
+					Completion        string `json:"completion"`
+					InputCacheRead    string `json:"input_cache_read"`
+					InternalReasoning string `json:"internal_reasoning"`
+				} `json:"pricing"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Data {
+			info := sdk.ModelInfo{
+				ID:          m.ID,
+				DisplayName: m.Name,
+				Provider:    provider,
+				OwnedBy:     m.OwnedBy,
+				MaxOutput:   m.TopProvider.MaxCompletionTokens,
+			}
+			if info.DisplayName == "" {
+				info.DisplayName = m.ID
+			}
+			for _, n := range []int{m.ContextWindow, m.MaxContextLength, m.ContextLength} {
+				if n > 0 {
+					info.ContextLength = n
+				}
+			}
+			if m.Created > 0 {
+				info.Created = time.Unix(m.Created, 0)
+			}
+			if m.Pricing != nil {
+				info.Pricing = &sdk.ModelPrice{
+					Input:       perMillion(m.Pricing.Prompt),
+					Output:      perMillion(m.Pricing.Completion),
+					CachedInput: perMillion(m.Pricing.InputCacheRead),
+					Reasoning:   perMillion(m.Pricing.InternalReasoning),
+				}
+			}
+			models = append(models, info)
+		}
+
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		after = page.LastID
+	}
+}
+
+// converts a per token USD price string to USD per million tokens
+func perMillion(price string) float64 {
+	v, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return 0
+	}
+	return v * 1_000_000
+}
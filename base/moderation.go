@@ -0,0 +1,64 @@
+// shared moderation for OpenAI compatible APIs
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// classifies inputs with an OpenAI compatible POST /moderations endpoint
+// categories maps the provider category names to the SDK ones
+func (p *Provider) ModerateOpenAI(ctx context.Context, endpoint string, header http.Header, model string, inputs []string, categories map[string]sdk.ModerationCategory) ([]sdk.ModerationResult, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Results []struct {
+			Flagged        *bool              `json:"flagged"` // missing in mistral responses
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	results := make([]sdk.ModerationResult, 0, len(parsed.Results))
+	for _, r := range parsed.Results {
+		flagged := false
+		if r.Flagged != nil {
+			flagged = *r.Flagged
+		} else {
+			for _, f := range r.Categories {
+				flagged = flagged || f
+			}
+		}
+		results = append(results, sdk.NewModerationResult(flagged, r.Categories, r.CategoryScores, categories))
+	}
+	return results, nil
+}
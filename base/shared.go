@@ -7,12 +7,31 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"sort"
 
 	"github.com/xerohard/ai/v2/sdk"
 )
 
 // parses a streaming JSON response and calls onChunk for each content chunk
 func ParseJsonStream(body io.Reader, onChunk func(string) error) error {
+	return ParseJsonEvents(body, func(event sdk.StreamEvent) error {
+		if event.Type == sdk.StreamEventText {
+			return onChunk(event.Text)
+		}
+		return nil
+	})
+}
+
+// parses a streaming JSON response into text and reasoning events
+// reasoning is read from delta.reasoning (OpenRouter, Groq) or else delta.reasoning_content (DeepSeek style, xAI)
+func ParseJsonEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return ReadEventData(body, func(data []byte) error {
+		return JsonChunkEvents(data, onEvent)
+	})
+}
+
+// calls onData with the payload of every line of an event stream until [DONE]
+func ReadEventData(body io.Reader, onData func([]byte) error) error {
 	reader := bufio.NewReader(body)
 
 	for {
@@ -28,23 +47,8 @@ func ParseJsonStream(body io.Reader, onChunk func(string) error) error {
 			if bytes.Equal(line, []byte("[DONE]")) {
 				return nil
 			}
-
-			var chunk struct {
-				Choices []struct {
-					Delta struct {
-						Content string `json:"content"`
-					} `json:"delta"`
-				} `json:"choices"`
-			}
-
-			if err := json.Unmarshal(line, &chunk); err == nil {
-				for _, c := range chunk.Choices {
-					if c.Delta.Content != "" {
-						if err := onChunk(c.Delta.Content); err != nil {
-							return err
-						}
-					}
-				}
+			if err := onData(line); err != nil {
+				return err
 			}
 		}
 
@@ -57,6 +61,49 @@ func ParseJsonStream(body io.Reader, onChunk func(string) error) error {
 	}
 }
 
+// emits the text, reasoning and usage events of one streamed chunk, invalid chunks are skipped
+// usage is read from usage or, for Groq, x_groq.usage
+func JsonChunkEvents(data []byte, onEvent func(sdk.StreamEvent) error) error {
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content          string `json:"content"`
+				Reasoning        string `json:"reasoning"`
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Usage *jsonUsage `json:"usage"`
+		XGroq struct {
+			Usage *jsonUsage `json:"usage"`
+		} `json:"x_groq"`
+	}
+
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil
+	}
+	for _, c := range chunk.Choices {
+		if reasoning := firstNonEmpty(c.Delta.Reasoning, c.Delta.ReasoningContent); reasoning != "" {
+			if err := onEvent(sdk.StreamEvent{Type: sdk.StreamEventReasoning, Text: reasoning}); err != nil {
+				return err
+			}
+		}
+		if c.Delta.Content != "" {
+			if err := onEvent(sdk.StreamEvent{Type: sdk.StreamEventText, Text: c.Delta.Content}); err != nil {
+				return err
+			}
+		}
+	}
+
+	usage := chunk.Usage
+	if usage == nil {
+		usage = chunk.XGroq.Usage
+	}
+	if usage != nil && usage.PromptTokens+usage.CompletionTokens > 0 {
+		return onEvent(sdk.StreamEvent{Type: sdk.StreamEventUsage, Usage: usage.usage()})
+	}
+	return nil
+}
+
 // extracts a CompletionResponse from a JSON response body
 func ExtractJsonResponse(body []byte) (*sdk.CompletionResponse, error) {
 
@@ -69,10 +116,24 @@ func ExtractJsonResponse(body []byte) (*sdk.CompletionResponse, error) {
 	}
 
 	var parsed struct {
+		Model   string `json:"model"`
 		Choices []struct {
-			Message struct {
-				Role      string `json:"role"`
-				Content   string `json:"content,omitempty"`
+			FinishReason string `json:"finish_reason"`
+			Message      struct {
+				Role             string `json:"role"`
+				Content          string `json:"content,omitempty"`
+				Reasoning        string `json:"reasoning,omitempty"`
+				ReasoningContent string `json:"reasoning_content,omitempty"`
+				Annotations      []struct {
+					Type        string `json:"type"`
+					URLCitation struct {
+						URL        string `json:"url"`
+						Title      string `json:"title"`
+						Content    string `json:"content"`
+						StartIndex int    `json:"start_index"`
+						EndIndex   int    `json:"end_index"`
+					} `json:"url_citation"`
+				} `json:"annotations,omitempty"`
 				ToolCalls []struct {
 					ID        string `json:"id"`
 					Name      string `json:"name"`
@@ -80,6 +141,7 @@ func ExtractJsonResponse(body []byte) (*sdk.CompletionResponse, error) {
 				} `json:"tool_calls,omitempty"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage jsonUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &parsed); err != nil {
@@ -102,9 +164,107 @@ func ExtractJsonResponse(body []byte) (*sdk.CompletionResponse, error) {
 		})
 	}
 
+	// url citations (OpenAI and OpenRouter web search) index characters, converted to byte offsets
+	var citations []sdk.Citation
+	for _, a := range msg.Annotations {
+		if a.Type != "url_citation" {
+			continue
+		}
+		citations = append(citations, sdk.Citation{
+			URL:     a.URLCitation.URL,
+			Title:   a.URLCitation.Title,
+			Snippet: a.URLCitation.Content,
+			Start:   byteOffset(msg.Content, a.URLCitation.StartIndex),
+			End:     byteOffset(msg.Content, a.URLCitation.EndIndex),
+		})
+	}
+
 	return &sdk.CompletionResponse{
-		Content:   msg.Content,
-		ToolCalls: toolCalls,
-		Role:      msg.Role,
+		Content:      msg.Content,
+		Reasoning:    firstNonEmpty(msg.Reasoning, msg.ReasoningContent),
+		Citations:    citations,
+		ToolCalls:    toolCalls,
+		Role:         msg.Role,
+		Model:        parsed.Model,
+		FinishReason: parsed.Choices[0].FinishReason,
+		Usage:        parsed.Usage.usage(),
 	}, nil
 }
+
+// servers moving from reasoning_content to reasoning may send both with the same text
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// usage object of OpenAI compatible responses and final stream chunks
+type jsonUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u *jsonUsage) usage() sdk.Usage {
+	return sdk.Usage{
+		InputTokens:       u.PromptTokens,
+		OutputTokens:      u.CompletionTokens,
+		TotalTokens:       u.TotalTokens,
+		CachedInputTokens: u.PromptTokensDetails.CachedTokens,
+		ReasoningTokens:   u.CompletionTokensDetails.ReasoningTokens,
+	}
+}
+
+// byte offset of the nth character of s, clamped to len(s)
+func byteOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+// converts a tool input schema to a JSON schema object
+func ToolParameters(schema sdk.InputSchema) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for _, name := range SortedKeys(schema) {
+		prop := schema[name]
+		def := map[string]interface{}{"type": prop.Type}
+		if prop.Description != "" {
+			def["description"] = prop.Description
+		}
+		properties[name] = def
+		if prop.Required {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// returns the keys of a map in sorted order, keeps request bodies deterministic
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package base
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func TestJsonChunkReasoningFields(t *testing.T) {
+	tests := []struct {
+		name, delta, want string
+	}{
+		{"reasoning", `{"reasoning":"step one"}`, "step one"},
+		{"reasoning_content", `{"reasoning_content":"step one"}`, "step one"},
+		{"both with the same text", `{"reasoning":"step one","reasoning_content":"step one"}`, "step one"},
+		{"reasoning preferred", `{"reasoning":"new","reasoning_content":"old"}`, "new"},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := JsonChunkEvents([]byte(`{"choices":[{"delta":`+tt.delta+`}]}`), func(e sdk.StreamEvent) error {
+			if e.Type == sdk.StreamEventReasoning {
+				got = append(got, e.Text)
+			}
+			return nil
+		})
+		if err != nil || strings.Join(got, "|") != tt.want {
+			t.Errorf("%s: reasoning events = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExtractJsonReasoningFields(t *testing.T) {
+	tests := []struct {
+		name, fields, want string
+	}{
+		{"reasoning", `"reasoning":"why"`, "why"},
+		{"reasoning_content", `"reasoning_content":"why"`, "why"},
+		{"both with the same text", `"reasoning":"why","reasoning_content":"why"`, "why"},
+	}
+	for _, tt := range tests {
+		resp, err := ExtractJsonResponse([]byte(`{"choices":[{"message":{"role":"assistant","content":"answer",` + tt.fields + `}}]}`))
+		if err != nil || resp.Reasoning != tt.want || resp.Content != "answer" {
+			t.Errorf("%s: response = %+v, %v", tt.name, resp, err)
+		}
+	}
+}
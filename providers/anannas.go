@@ -26,6 +26,10 @@ func NewAnannasProvider(apiKey string) *AnannasProvider {
 	return p
 }
 
+func (p *AnannasProvider) Name() string {
+	return "anannas"
+}
+
 func (p *AnannasProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.anannas.ai/v1/chat/completions"
 
@@ -71,24 +75,32 @@ func (p *AnannasProvider) CallAPI(ctx context.Context, messages []sdk.Message, s
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
-	}
-
 	return resp.Body, nil
 }
 
+// lists models with GET /v1/models
+func (p *AnannasProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://api.anannas.ai/v1/models", p.Name(), http.Header{"Authorization": {"Bearer " + p.APIKey}})
+}
+
+// embeds a batch with POST /v1/embeddings
+func (p *AnannasProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	return p.EmbedOpenAI(ctx, "https://api.anannas.ai/v1/embeddings", http.Header{"Authorization": {"Bearer " + p.APIKey}}, req, nil)
+}
+
+func (p *AnannasProvider) MaxEmbedInputs() int {
+	return 512
+}
+
 func (p *AnannasProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *AnannasProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
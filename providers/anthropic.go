@@ -9,11 +9,15 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/xerohard/ai/v2/base"
 	"github.com/xerohard/ai/v2/sdk"
 )
 
+const anthropicVersion = "2023-06-01"
+
 type AnthropicProvider struct {
 	*base.Provider
 	APIKey string
@@ -27,6 +31,12 @@ func NewAnthropicProvider(apiKey string) *AnthropicProvider {
 	return p
 }
 
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// sends a Messages API request with the anthropic-version header
+// tools are sent with the request, tool calls and results in the history become tool_use and tool_result blocks
 func (p *AnthropicProvider) CallAPI(
 	ctx context.Context,
 	messages []sdk.Message,
@@ -35,40 +45,101 @@ func (p *AnthropicProvider) CallAPI(
 ) (io.ReadCloser, error) {
 	url := "https://api.anthropic.com/v1/messages"
 
-	chatMessages := []map[string]string{}
-	for _, m := range messages {
-		chatMessages = append(chatMessages, map[string]string{
-			"role":    m.Role,
-			"content": m.Content,
-		})
+	body := p.messageParams(messages, opts)
+	body["stream"] = streamMode
+
+	return p.post(ctx, url, body)
+}
+
+// Messages API parameters, shared by CallAPI and message batches
+func (p *AnthropicProvider) messageParams(messages []sdk.Message, opts *sdk.Options) map[string]interface{} {
+	body := p.buildRequest(messages, opts)
+	maxTokens := 1024
+
+	if opts != nil {
+		if opts.MaxCompletionTokens != 0 {
+			maxTokens = opts.MaxCompletionTokens
+		}
+		if opts.Temperature != 0 {
+			body["temperature"] = opts.Temperature
+		}
+
+		if budget := thinkingBudget(opts); budget > 0 {
+			body["thinking"] = map[string]interface{}{
+				"type":          "enabled",
+				"budget_tokens": budget,
+			}
+			// max_tokens includes the thinking budget, and thinking does not allow a custom temperature
+			if maxTokens <= budget {
+				maxTokens = budget + 1024
+			}
+			delete(body, "temperature")
+		}
 	}
+	body["max_tokens"] = maxTokens
+	return body
+}
 
-	var systemPrompt string
-	if len(chatMessages) > 0 && chatMessages[0]["role"] == "system" {
-		systemPrompt = chatMessages[0]["content"]
-		chatMessages = chatMessages[1:]
+// thinking budgets used for ReasoningEffort when no ThinkingBudget is set
+var anthropicThinkingBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// extended thinking budget in tokens, 0 disables thinking
+func thinkingBudget(opts *sdk.Options) int {
+	if opts.ThinkingBudget > 0 {
+		return max(opts.ThinkingBudget, 1024) // the minimum accepted budget
 	}
+	return anthropicThinkingBudgets[opts.ReasoningEffort]
+}
+
+// counts prompt tokens with the count_tokens endpoint
+func (p *AnthropicProvider) CountTokens(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (int, error) {
+	url := "https://api.anthropic.com/v1/messages/count_tokens"
+
+	messages = p.AddSystemPrompt(messages, opts)
+
+	body, err := p.post(ctx, url, p.buildRequest(messages, opts))
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return 0, err
+	}
+	return result.InputTokens, nil
+}
+
+// builds the model, system prompt, messages and tools of a request
+// shared by CallAPI and CountTokens
+func (p *AnthropicProvider) buildRequest(messages []sdk.Message, opts *sdk.Options) map[string]interface{} {
+	systemPrompt, chatMessages := convertAnthropicMessages(messages)
 
 	body := map[string]interface{}{
-		"system":     systemPrompt,
-		"messages":   chatMessages,
-		"stream":     streamMode,
-		"max_tokens": 1024,
+		"messages": chatMessages,
+	}
+	if systemPrompt != nil {
+		body["system"] = systemPrompt
 	}
 	if opts != nil {
 		if opts.Model != "" {
 			body["model"] = opts.Model
 		}
-		if opts.MaxCompletionTokens != 0 {
-			body["max_tokens"] = opts.MaxCompletionTokens
-		}
-		if opts.ReasoningEffort != "" {
-			body["reasoning_effort"] = opts.ReasoningEffort
-		}
-		if opts.Temperature != 0 {
-			body["temperature"] = opts.Temperature
+		if len(opts.Tools) > 0 {
+			body["tools"] = convertAnthropicTools(opts.Tools)
 		}
 	}
+	return body
+}
+
+func (p *AnthropicProvider) post(ctx context.Context, url string, body interface{}) (io.ReadCloser, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
@@ -78,27 +149,285 @@ func (p *AnthropicProvider) CallAPI(
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("x-api-key", p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
+	return p.send(req)
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func (p *AnthropicProvider) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
+	return p.send(req)
+}
+
+func (p *AnthropicProvider) send(req *http.Request) (io.ReadCloser, error) {
+	req.Header.Set("x-api-key", p.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	return resp.Body, nil
 }
 
+// lists models with GET /v1/models, following the after_id pagination
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	var models []sdk.ModelInfo
+	afterID := ""
+	for {
+		endpoint := "https://api.anthropic.com/v1/models?limit=1000"
+		if afterID != "" {
+			endpoint += "&after_id=" + url.QueryEscape(afterID)
+		}
+
+		body, err := p.get(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Data []struct {
+				ID          string    `json:"id"`
+				DisplayName string    `json:"display_name"`
+				CreatedAt   time.Time `json:"created_at"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		err = json.NewDecoder(body).Decode(&page)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Data {
+			models = append(models, sdk.ModelInfo{
+				ID:          m.ID,
+				DisplayName: m.DisplayName,
+				Provider:    p.Name(),
+				OwnedBy:     "anthropic",
+				Created:     m.CreatedAt,
+			})
+		}
+
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// extracts text and tool_use blocks from a Messages API response
+func (p *AnthropicProvider) ExtractResponse(body []byte) (*sdk.CompletionResponse, error) {
+	var parsed struct {
+		Model      string         `json:"model"`
+		Role       string         `json:"role"`
+		StopReason string         `json:"stop_reason"`
+		Usage      anthropicUsage `json:"usage"`
+		Content    []struct {
+			Type      string          `json:"type"`
+			Text      string          `json:"text"`
+			ID        string          `json:"id"`
+			Name      string          `json:"name"`
+			Input     json.RawMessage `json:"input"`
+			Thinking  string          `json:"thinking"`
+			Signature string          `json:"signature"`
+			Data      string          `json:"data"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+
+	resp := &sdk.CompletionResponse{
+		Role:         parsed.Role,
+		Model:        parsed.Model,
+		FinishReason: parsed.StopReason,
+		Usage:        parsed.Usage.usage(),
+	}
+	for _, block := range parsed.Content {
+		switch block.Type {
+		case "text":
+			resp.Content += block.Text
+		case "tool_use":
+			resp.ToolCalls = append(resp.ToolCalls, sdk.ToolCallRequest{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: block.Input,
+			})
+		case "thinking":
+			resp.Reasoning += block.Thinking
+			resp.ReasoningBlocks = append(resp.ReasoningBlocks, sdk.ReasoningBlock{Type: block.Type, Thinking: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			resp.ReasoningBlocks = append(resp.ReasoningBlocks, sdk.ReasoningBlock{Type: block.Type, Data: block.Data})
+		}
+	}
+	return resp, nil
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// input_tokens excludes the tokens read from or written to the prompt cache
+func (u *anthropicUsage) usage() sdk.Usage {
+	input := u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+	return sdk.Usage{
+		InputTokens:         input,
+		OutputTokens:        u.OutputTokens,
+		TotalTokens:         input + u.OutputTokens,
+		CachedInputTokens:   u.CacheReadInputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// converts messages to the Messages API format
+// system messages are lifted into the system prompt, tool results are sent as user tool_result blocks
+// the system prompt is a string, or text blocks when a system message has a cache breakpoint
+func convertAnthropicMessages(messages []sdk.Message) (interface{}, []map[string]interface{}) {
+	var systemPrompt string
+	var systemBlocks []interface{}
+	systemCached := false
+	chatMessages := []map[string]interface{}{}
+	toolResults := -1 // index of the user message collecting consecutive tool results
+
+	for _, m := range messages {
+		switch {
+		case m.Role == "system":
+			if systemPrompt != "" {
+				systemPrompt += "\n\n"
+			}
+			systemPrompt += m.Content
+			systemBlocks = append(systemBlocks, withCacheControl(map[string]interface{}{"type": "text", "text": m.Content}, m.CacheControl))
+			systemCached = systemCached || m.CacheControl != nil
+			continue
+
+		case m.Role == "tool":
+			block := withCacheControl(map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     m.Content,
+			}, m.CacheControl)
+			if toolResults >= 0 {
+				blocks := chatMessages[toolResults]["content"].([]interface{})
+				chatMessages[toolResults]["content"] = append(blocks, block)
+				continue
+			}
+			chatMessages = append(chatMessages, map[string]interface{}{
+				"role":    "user",
+				"content": []interface{}{block},
+			})
+			toolResults = len(chatMessages) - 1
+			continue
+
+		case len(m.ToolCalls) > 0:
+			content := thinkingBlocks(m.ReasoningBlocks)
+			if m.Content != "" {
+				content = append(content, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input := tc.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				content = append(content, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Name,
+					"input": input,
+				})
+			}
+			withCacheControl(content[len(content)-1].(map[string]interface{}), m.CacheControl)
+			chatMessages = append(chatMessages, map[string]interface{}{
+				"role":    m.Role,
+				"content": content,
+			})
+
+		case m.CacheControl != nil || len(m.ReasoningBlocks) > 0:
+			content := thinkingBlocks(m.ReasoningBlocks)
+			content = append(content, withCacheControl(map[string]interface{}{"type": "text", "text": m.Content}, m.CacheControl))
+			chatMessages = append(chatMessages, map[string]interface{}{
+				"role":    m.Role,
+				"content": content,
+			})
+
+		default:
+			chatMessages = append(chatMessages, map[string]interface{}{
+				"role":    m.Role,
+				"content": m.Content,
+			})
+		}
+		toolResults = -1
+	}
+
+	if systemCached {
+		return systemBlocks, chatMessages
+	}
+	if systemPrompt == "" {
+		return nil, chatMessages
+	}
+	return systemPrompt, chatMessages
+}
+
+// signed thinking blocks, sent back unchanged before the text and tool_use blocks of assistant turns
+func thinkingBlocks(blocks []sdk.ReasoningBlock) []interface{} {
+	content := []interface{}{}
+	for _, b := range blocks {
+		switch b.Type {
+		case "thinking":
+			content = append(content, map[string]interface{}{"type": "thinking", "thinking": b.Thinking, "signature": b.Signature})
+		case "redacted_thinking":
+			content = append(content, map[string]interface{}{"type": "redacted_thinking", "data": b.Data})
+		}
+	}
+	return content
+}
+
+// adds a cache_control breakpoint to a content block or tool definition
+func withCacheControl(block map[string]interface{}, cc *sdk.CacheControl) map[string]interface{} {
+	if cc == nil {
+		return block
+	}
+	control := map[string]interface{}{"type": "ephemeral"}
+	if cc.TTL != "" {
+		control["ttl"] = cc.TTL
+	}
+	block["cache_control"] = control
+	return block
+}
+
+func convertAnthropicTools(tools map[string]sdk.Tool) []map[string]interface{} {
+	converted := make([]map[string]interface{}, 0, len(tools))
+	for _, name := range base.SortedKeys(tools) {
+		tool := tools[name]
+		converted = append(converted, withCacheControl(map[string]interface{}{
+			"name":         name,
+			"description":  tool.Description,
+			"input_schema": base.ToolParameters(tool.InputSchema),
+		}, tool.CacheControl))
+	}
+	return converted
+}
+
 func (p *AnthropicProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
+	return p.ParseEvents(body, func(event sdk.StreamEvent) error {
+		if event.Type == sdk.StreamEventText {
+			return onChunk(event.Text)
+		}
+		return nil
+	})
+}
+
+// emits text_delta and thinking_delta events of content_block_delta
+// and the usage of message_start, updated by every message_delta
+func (p *AnthropicProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
 	reader := bufio.NewReader(body)
+	var usage anthropicUsage
 
 	for {
 		line, err := reader.ReadBytes('\n')
@@ -116,13 +445,38 @@ func (p *AnthropicProvider) ParseResponse(body io.Reader, onChunk func(string) e
 			var evt struct {
 				Type  string `json:"type"`
 				Delta struct {
-					Text string `json:"text"`
+					Type     string `json:"type"`
+					Text     string `json:"text"`
+					Thinking string `json:"thinking"`
 				} `json:"delta"`
+				Message struct {
+					Usage anthropicUsage `json:"usage"`
+				} `json:"message"`
+				Usage anthropicUsage `json:"usage"`
 			}
 
 			if err := json.Unmarshal(line, &evt); err == nil {
-				if evt.Type == "content_block_delta" && evt.Delta.Text != "" {
-					if err := onChunk(evt.Delta.Text); err != nil {
+				switch evt.Type {
+				case "message_start":
+					usage = evt.Message.Usage
+				case "message_delta":
+					// output_tokens is cumulative
+					usage.OutputTokens = evt.Usage.OutputTokens
+					if err := onEvent(sdk.StreamEvent{Type: sdk.StreamEventUsage, Usage: usage.usage()}); err != nil {
+						return err
+					}
+				}
+				if evt.Type == "content_block_delta" {
+					var event sdk.StreamEvent
+					switch {
+					case evt.Delta.Type == "thinking_delta" && evt.Delta.Thinking != "":
+						event = sdk.StreamEvent{Type: sdk.StreamEventReasoning, Text: evt.Delta.Thinking}
+					case evt.Delta.Text != "":
+						event = sdk.StreamEvent{Type: sdk.StreamEventText, Text: evt.Delta.Text}
+					default:
+						continue
+					}
+					if err := onEvent(event); err != nil {
 						return err
 					}
 				}
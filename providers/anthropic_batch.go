@@ -0,0 +1,159 @@
+// Anthropic Message Batches API
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+type anthropicBatch struct {
+	ID               string    `json:"id"`
+	ProcessingStatus string    `json:"processing_status"`
+	CreatedAt        time.Time `json:"created_at"`
+	ResultsURL       string    `json:"results_url"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+func (b *anthropicBatch) toBatch() *sdk.Batch {
+	counts := b.RequestCounts
+	batch := &sdk.Batch{
+		ID:             b.ID,
+		Status:         sdk.BatchInProgress,
+		ProviderStatus: b.ProcessingStatus,
+		Total:          counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired,
+		Completed:      counts.Succeeded,
+		Failed:         counts.Errored + counts.Canceled + counts.Expired,
+		CreatedAt:      b.CreatedAt,
+	}
+	switch b.ProcessingStatus {
+	case "canceling":
+		batch.Status = sdk.BatchCancelling
+	case "ended":
+		// a batch without any succeeded request reports why none succeeded
+		switch {
+		case counts.Succeeded == 0 && counts.Canceled > 0:
+			batch.Status = sdk.BatchCancelled
+		case counts.Succeeded == 0 && counts.Expired > 0:
+			batch.Status = sdk.BatchExpired
+		case counts.Succeeded == 0 && counts.Errored > 0:
+			batch.Status = sdk.BatchFailed
+		default:
+			batch.Status = sdk.BatchCompleted
+		}
+	}
+	if b.ResultsURL != "" {
+		batch.Results = []string{b.ResultsURL}
+	}
+	return batch
+}
+
+// creates a message batch, the params are built like in CallAPI
+func (p *AnthropicProvider) SubmitBatch(ctx context.Context, items []sdk.BatchItem) (*sdk.Batch, error) {
+	requests := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		messages := p.AddSystemPrompt(item.Messages, item.Options)
+		requests = append(requests, map[string]interface{}{
+			"custom_id": item.CustomID,
+			"params":    p.messageParams(messages, item.Options),
+		})
+	}
+
+	body, err := p.post(ctx, "https://api.anthropic.com/v1/messages/batches", map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var batch anthropicBatch
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		return nil, err
+	}
+	return batch.toBatch(), nil
+}
+
+func (p *AnthropicProvider) GetBatch(ctx context.Context, id string) (*sdk.Batch, error) {
+	body, err := p.get(ctx, "https://api.anthropic.com/v1/messages/batches/"+id)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var batch anthropicBatch
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		return nil, err
+	}
+	return batch.toBatch(), nil
+}
+
+func (p *AnthropicProvider) CancelBatch(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages/batches/"+id+"/cancel", nil)
+	if err != nil {
+		return err
+	}
+	body, err := p.send(req)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// reads the JSONL results of an ended batch
+func (p *AnthropicProvider) BatchResults(ctx context.Context, batch *sdk.Batch, fn func(sdk.BatchResult) error) error {
+	if len(batch.Results) == 0 {
+		var err error
+		if batch, err = p.GetBatch(ctx, batch.ID); err != nil {
+			return err
+		}
+		if len(batch.Results) == 0 {
+			return fmt.Errorf("batch %s has no results yet", batch.ID)
+		}
+	}
+
+	body, err := p.get(ctx, batch.Results[0])
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return readJSONLines(body, func(line []byte) error {
+		var parsed struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string          `json:"type"` // succeeded, errored, canceled or expired
+				Message json.RawMessage `json:"message"`
+				Error   struct {
+					Error struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(line, &parsed); err != nil {
+			return err
+		}
+
+		result := sdk.BatchResult{CustomID: parsed.CustomID}
+		switch parsed.Result.Type {
+		case "succeeded":
+			result.Response, result.Err = p.ExtractResponse(parsed.Result.Message)
+		case "errored":
+			result.Err = fmt.Errorf("%s: %s", parsed.Result.Error.Error.Type, parsed.Result.Error.Error.Message)
+		default:
+			result.Err = fmt.Errorf("request %s", parsed.Result.Type)
+		}
+		return fn(result)
+	})
+}
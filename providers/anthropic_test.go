@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/base"
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// replies with status and body, and records the last request and its body
+type recorder struct {
+	req  *http.Request
+	body map[string]interface{}
+}
+
+func (r *recorder) client(status int, body string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		r.req = req
+		r.body = nil
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &r.body)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}
+}
+
+func newTestAnthropic(rec *recorder, status int, body string) *AnthropicProvider {
+	p := NewAnthropicProvider("test-key")
+	p.Provider = &base.Provider{APICaller: p, HTTPClient: rec.client(status, body)}
+	return p
+}
+
+func TestAnthropicCompletionRequest(t *testing.T) {
+	rec := &recorder{}
+	p := newTestAnthropic(rec, http.StatusOK, `{"role":"assistant","content":[{"type":"text","text":"ok"}]}`)
+
+	messages := []sdk.Message{
+		{Role: "user", Content: "weather in Paris and Rome?"},
+		{Role: "assistant", ToolCalls: []sdk.ToolCallRequest{
+			{ID: "call-1", Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			{ID: "call-2", Name: "weather", Arguments: json.RawMessage(`{"city":"Rome"}`)},
+		}},
+		{Role: "tool", ToolCallID: "call-1", Content: "sunny"},
+		{Role: "tool", ToolCallID: "call-2", Content: "rainy"},
+	}
+	opts := &sdk.Options{
+		Model:        "claude-sonnet-4-5",
+		SystemPrompt: "be brief",
+		Tools: map[string]sdk.Tool{
+			"weather": {Description: "current weather", InputSchema: sdk.InputSchema{"city": {Type: "string", Required: true}}},
+		},
+	}
+
+	if _, err := p.CreateCompletion(context.Background(), messages, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.req.Header.Get("anthropic-version"); got != anthropicVersion {
+		t.Errorf("anthropic-version = %q", got)
+	}
+	if got := rec.req.Header.Get("x-api-key"); got != "test-key" {
+		t.Errorf("x-api-key = %q", got)
+	}
+	if rec.body["system"] != "be brief" {
+		t.Errorf("system = %v", rec.body["system"])
+	}
+
+	tools := rec.body["tools"].([]interface{})
+	if len(tools) != 1 || tools[0].(map[string]interface{})["name"] != "weather" {
+		t.Errorf("tools = %v", tools)
+	}
+
+	sent := rec.body["messages"].([]interface{})
+	if len(sent) != 3 {
+		t.Fatalf("got %d messages, want user, assistant and one merged tool result message", len(sent))
+	}
+
+	assistant := sent[1].(map[string]interface{})["content"].([]interface{})
+	if len(assistant) != 2 || assistant[0].(map[string]interface{})["type"] != "tool_use" {
+		t.Errorf("assistant content = %v", assistant)
+	}
+
+	results := sent[2].(map[string]interface{})
+	blocks := results["content"].([]interface{})
+	if results["role"] != "user" || len(blocks) != 2 {
+		t.Fatalf("tool results = %v", results)
+	}
+	for i, id := range []string{"call-1", "call-2"} {
+		block := blocks[i].(map[string]interface{})
+		if block["type"] != "tool_result" || block["tool_use_id"] != id {
+			t.Errorf("tool result %d = %v", i, block)
+		}
+	}
+}
+
+func TestAnthropicCompletionResponse(t *testing.T) {
+	rec := &recorder{}
+	p := newTestAnthropic(rec, http.StatusOK, `{
+		"model": "claude-sonnet-4-5",
+		"role": "assistant",
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 20, "cache_read_input_tokens": 5},
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "call-1", "name": "weather", "input": {"city": "Paris"}}
+		]
+	}`)
+
+	resp, err := p.CreateCompletion(context.Background(), []sdk.Message{{Role: "user", Content: "weather?"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Content != "Let me check." || resp.FinishReason != "tool_use" || resp.Model != "claude-sonnet-4-5" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call-1" || string(resp.ToolCalls[0].Arguments) != `{"city": "Paris"}` {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage.InputTokens != 15 || resp.Usage.CachedInputTokens != 5 || resp.Usage.TotalTokens != 35 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicCompletionError(t *testing.T) {
+	rec := &recorder{}
+	p := newTestAnthropic(rec, http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+
+	_, err := p.CreateCompletion(context.Background(), []sdk.Message{{Role: "user", Content: "hi"}}, nil)
+	var apiErr *sdk.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want an APIError with status 400", err)
+	}
+}
+
+func TestAnthropicStreamUsage(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":4,"output_tokens":1}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"hi"}}
+
+event: message_delta
+data: {"type":"message_delta","usage":{"output_tokens":6}}
+`
+	var usage sdk.Usage
+	err := NewAnthropicProvider("").ParseEvents(strings.NewReader(stream), func(event sdk.StreamEvent) error {
+		if event.Type == sdk.StreamEventUsage {
+			usage = event.Usage
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.InputTokens != 14 || usage.CachedInputTokens != 4 || usage.OutputTokens != 6 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
@@ -0,0 +1,151 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// serves bodies by request path, other paths answer 404
+func filesClient(files map[string]string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, ok := files[req.URL.Path]
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}
+}
+
+func collectResults(t *testing.T, p sdk.BatchProvider, batch *sdk.Batch) map[string]sdk.BatchResult {
+	results := map[string]sdk.BatchResult{}
+	err := p.BatchResults(context.Background(), batch, func(r sdk.BatchResult) error {
+		results[r.CustomID] = r
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestOpenAIBatchStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   sdk.BatchStatus
+		done   bool
+	}{
+		{"validating", sdk.BatchInProgress, false},
+		{"in_progress", sdk.BatchInProgress, false},
+		{"finalizing", sdk.BatchInProgress, false},
+		{"cancelling", sdk.BatchCancelling, false},
+		{"completed", sdk.BatchCompleted, true},
+		{"failed", sdk.BatchFailed, true},
+		{"expired", sdk.BatchExpired, true},
+		{"cancelled", sdk.BatchCancelled, true},
+	}
+	for _, tt := range tests {
+		b := &openAIBatch{ID: "batch_1", Status: tt.status, OutputFileID: "file-out", ErrorFileID: "file-err"}
+		batch := b.toBatch()
+		if batch.Status != tt.want || batch.Status.Done() != tt.done || batch.ProviderStatus != tt.status {
+			t.Errorf("%s: status %s, done %v", tt.status, batch.Status, batch.Status.Done())
+		}
+		if len(batch.Results) != 2 || batch.Results[0] != "file-out" {
+			t.Errorf("%s: results = %v", tt.status, batch.Results)
+		}
+	}
+}
+
+func TestAnthropicBatchStatus(t *testing.T) {
+	type counts struct{ processing, succeeded, errored, canceled, expired int }
+	tests := []struct {
+		name   string
+		status string
+		counts counts
+		want   sdk.BatchStatus
+	}{
+		{"running", "in_progress", counts{processing: 3}, sdk.BatchInProgress},
+		{"canceling", "canceling", counts{processing: 2, succeeded: 1}, sdk.BatchCancelling},
+		{"succeeded", "ended", counts{succeeded: 3}, sdk.BatchCompleted},
+		{"partly errored", "ended", counts{succeeded: 2, errored: 1}, sdk.BatchCompleted},
+		{"all errored", "ended", counts{errored: 3}, sdk.BatchFailed},
+		{"cancelled", "ended", counts{canceled: 2, errored: 1}, sdk.BatchCancelled},
+		{"expired", "ended", counts{expired: 3}, sdk.BatchExpired},
+	}
+	for _, tt := range tests {
+		b := &anthropicBatch{ID: "msgbatch_1", ProcessingStatus: tt.status}
+		b.RequestCounts.Processing = tt.counts.processing
+		b.RequestCounts.Succeeded = tt.counts.succeeded
+		b.RequestCounts.Errored = tt.counts.errored
+		b.RequestCounts.Canceled = tt.counts.canceled
+		b.RequestCounts.Expired = tt.counts.expired
+
+		batch := b.toBatch()
+		if batch.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, batch.Status, tt.want)
+		}
+		c := tt.counts
+		if batch.Total != c.processing+c.succeeded+c.errored+c.canceled+c.expired || batch.Completed != c.succeeded || batch.Failed != c.errored+c.canceled+c.expired {
+			t.Errorf("%s: counts = %d total, %d completed, %d failed", tt.name, batch.Total, batch.Completed, batch.Failed)
+		}
+	}
+}
+
+func TestOpenAIBatchResults(t *testing.T) {
+	p := NewOpenAiProvider("test-key")
+	p.HTTPClient = filesClient(map[string]string{
+		"/v1/files/file-out/content": `{"custom_id":"request-0","response":{"status_code":200,"body":{"model":"gpt-4o","choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}}}
+{"custom_id":"request-1","response":{"status_code":400,"body":{"error":{"message":"bad"}}}}
+`,
+		"/v1/files/file-err/content": `{"custom_id":"request-2","error":{"code":"batch_expired","message":"expired"}}`,
+	})
+
+	results := collectResults(t, p, &sdk.Batch{ID: "batch_1", Results: []string{"file-out", "file-err"}})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results["request-0"]; r.Err != nil || r.Response.Content != "hello" || r.Response.Usage.TotalTokens != 4 {
+		t.Errorf("request-0 = %+v", r)
+	}
+	var apiErr *sdk.APIError
+	if r := results["request-1"]; !errors.As(r.Err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("request-1 err = %v, want an APIError with status 400", r.Err)
+	}
+	if r := results["request-2"]; r.Err == nil || !strings.Contains(r.Err.Error(), "batch_expired") {
+		t.Errorf("request-2 err = %v", r.Err)
+	}
+}
+
+func TestAnthropicBatchResults(t *testing.T) {
+	p := NewAnthropicProvider("test-key")
+	p.HTTPClient = filesClient(map[string]string{
+		"/v1/messages/batches/msgbatch_1/results": `{"custom_id":"request-0","result":{"type":"succeeded","message":{"role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn","content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":3,"output_tokens":1}}}}
+{"custom_id":"request-1","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}}}
+{"custom_id":"request-2","result":{"type":"expired"}}
+`,
+	})
+
+	results := collectResults(t, p, &sdk.Batch{ID: "msgbatch_1", Results: []string{"https://api.anthropic.com/v1/messages/batches/msgbatch_1/results"}})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results["request-0"]; r.Err != nil || r.Response.Content != "hello" || r.Response.FinishReason != "end_turn" {
+		t.Errorf("request-0 = %+v", r)
+	}
+	if r := results["request-1"]; r.Err == nil || r.Err.Error() != "invalid_request_error: bad" {
+		t.Errorf("request-1 err = %v", r.Err)
+	}
+	if r := results["request-2"]; r.Err == nil || r.Err.Error() != "request expired" {
+		t.Errorf("request-2 err = %v", r.Err)
+	}
+}
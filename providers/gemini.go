@@ -6,10 +6,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/xerohard/ai/v2/base"
 	"github.com/xerohard/ai/v2/sdk"
@@ -28,6 +31,10 @@ func NewGeminiProvider(apiKey string) *GeminiProvider {
 	return p
 }
 
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
 type GeminiFunctionDeclaration struct {
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
@@ -51,10 +58,17 @@ type GeminiToolConfig struct {
 
 type GeminiPart struct {
 	Text             string                  `json:"text,omitempty"`
+	InlineData       *GeminiBlob             `json:"inlineData,omitempty"`
 	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
 	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
 }
 
+// inline media, data is base64 encoded
+type GeminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
 type GeminiFunctionResponse struct {
 	Name     string         `json:"name"`
 	Response map[string]any `json:"response"`
@@ -71,8 +85,9 @@ type GeminiContent struct {
 }
 
 type GenerationConfig struct {
-	Temperature     float32 `json:"temperature,omitempty"`
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	Temperature        float32  `json:"temperature,omitempty"`
+	MaxOutputTokens    int      `json:"maxOutputTokens,omitempty"`
+	ResponseModalities []string `json:"responseModalities,omitempty"`
 }
 
 type GeminiRequest struct {
@@ -88,16 +103,27 @@ type Candidate struct {
 }
 
 type GeminiResponseChunk struct {
-	Candidates []Candidate `json:"candidates"`
+	Candidates    []Candidate          `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
 }
 
 type PromptFeedback struct {
 	BlockReason string `json:"blockReason"`
 }
 
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
 type GeminiResponse struct {
-	Candidates     []Candidate     `json:"candidates"`
-	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	Candidates     []Candidate         `json:"candidates"`
+	PromptFeedback *PromptFeedback     `json:"promptFeedback,omitempty"`
+	UsageMetadata  GeminiUsageMetadata `json:"usageMetadata"`
+	ModelVersion   string              `json:"modelVersion,omitempty"`
 }
 
 type ContentBlockedError struct {
@@ -109,6 +135,10 @@ func (e *ContentBlockedError) Error() string {
 	return fmt.Sprintf("content blocked by safety filters. Finish Reason: %s. Response Body: %s", e.Reason, string(e.Body))
 }
 
+func (e *ContentBlockedError) Unwrap() error {
+	return sdk.ErrContentFiltered
+}
+
 func (p *GeminiProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 
 	var model string
@@ -123,6 +153,104 @@ func (p *GeminiProvider) CallAPI(ctx context.Context, messages []sdk.Message, st
 		url = fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, p.APIKey)
 	}
 
+	reqBody := p.buildRequest(messages, opts)
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// extracts text, function calls and usage from a generateContent response
+func (p *GeminiProvider) ExtractResponse(body []byte) (*sdk.CompletionResponse, error) {
+	var response GeminiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse non-streaming JSON response: %w. Body: %s", err, string(body))
+	}
+
+	if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+		return nil, &ContentBlockedError{
+			Reason: response.PromptFeedback.BlockReason,
+			Body:   body,
+		}
+	}
+
+	if len(response.Candidates) == 0 {
+		return nil, fmt.Errorf("non-streaming response body was successfully parsed but contained no candidates. Raw body: %s", string(body))
+	}
+
+	candidate := response.Candidates[0]
+
+	if candidate.FinishReason == "SAFETY" || candidate.FinishReason == "RECITATION" {
+		return nil, &ContentBlockedError{
+			Reason: candidate.FinishReason,
+			Body:   body,
+		}
+	}
+
+	var fullText string
+	var toolCalls []sdk.ToolCallRequest
+	for i, part := range candidate.Content.Parts {
+		if part.Text != "" {
+			fullText += part.Text
+		}
+
+		if part.FunctionCall != nil {
+			argsJSON, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal function call args: %w", err)
+			}
+
+			toolCalls = append(toolCalls, sdk.ToolCallRequest{
+				ID:        fmt.Sprintf("call_%d", i),
+				Name:      part.FunctionCall.Name,
+				Arguments: json.RawMessage(argsJSON),
+			})
+		}
+	}
+
+	if fullText == "" && len(toolCalls) == 0 {
+		return nil, fmt.Errorf("non-streaming response body was successfully parsed but contained empty text (FinishReason: %s). Raw body: %s", candidate.FinishReason, string(body))
+	}
+
+	return &sdk.CompletionResponse{
+		Content:      fullText,
+		ToolCalls:    toolCalls,
+		Role:         "assistant",
+		Model:        response.ModelVersion,
+		FinishReason: candidate.FinishReason,
+		Usage:        response.UsageMetadata.usage(),
+	}, nil
+}
+
+// output tokens include the thinking tokens
+func (m *GeminiUsageMetadata) usage() sdk.Usage {
+	return sdk.Usage{
+		InputTokens:       m.PromptTokenCount,
+		OutputTokens:      m.CandidatesTokenCount + m.ThoughtsTokenCount,
+		TotalTokens:       m.TotalTokenCount,
+		CachedInputTokens: m.CachedContentTokenCount,
+		ReasoningTokens:   m.ThoughtsTokenCount,
+	}
+}
+
+// converts messages, generation config and tools to a Gemini request
+// shared by CallAPI and CountTokens
+func (p *GeminiProvider) buildRequest(messages []sdk.Message, opts *sdk.Options) *GeminiRequest {
 	var systemInstruction *GeminiContent
 	var geminiContents []GeminiContent
 
@@ -197,7 +325,7 @@ func (p *GeminiProvider) CallAPI(ctx context.Context, messages []sdk.Message, st
 		}
 	}
 
-	reqBody := GeminiRequest{
+	reqBody := &GeminiRequest{
 		Contents:          geminiContents,
 		SystemInstruction: systemInstruction,
 	}
@@ -222,111 +350,65 @@ func (p *GeminiProvider) CallAPI(ctx context.Context, messages []sdk.Message, st
 		reqBody.GenerationConfig = cfg
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	return reqBody
+}
+
+// counts prompt tokens with the countTokens endpoint
+func (p *GeminiProvider) CountTokens(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (int, error) {
+	var model string
+	if opts != nil && opts.Model != "" {
+		model = opts.Model
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:countTokens?key=%s", model, p.APIKey)
+
+	messages = p.AddSystemPrompt(messages, opts)
+	generateRequest := p.buildRequest(messages, opts)
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"generateContentRequest": map[string]interface{}{
+			"model":             "models/" + model,
+			"contents":          generateRequest.Contents,
+			"systemInstruction": generateRequest.SystemInstruction,
+			"tools":             generateRequest.Tools,
+		},
+	})
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
-
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
+	var result struct {
+		TotalTokens int `json:"totalTokens"`
 	}
-
-	if !streamMode {
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var response GeminiResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			return nil, fmt.Errorf("failed to parse non-streaming JSON response: %w. Body: %s", err, string(body))
-		}
-
-		if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
-			return nil, &ContentBlockedError{
-				Reason: response.PromptFeedback.BlockReason,
-				Body:   body,
-			}
-		}
-
-		if len(response.Candidates) > 0 {
-			candidate := response.Candidates[0]
-
-			if candidate.FinishReason == "SAFETY" || candidate.FinishReason == "RECITATION" {
-				return nil, &ContentBlockedError{
-					Reason: candidate.FinishReason,
-					Body:   body,
-				}
-			}
-
-			var fullText string
-			var toolCalls []sdk.ToolCallRequest
-			for i, part := range candidate.Content.Parts {
-				if part.Text != "" {
-					fullText += part.Text
-				}
-
-				if part.FunctionCall != nil {
-					argsJSON, err := json.Marshal(part.FunctionCall.Args)
-					if err != nil {
-						return nil, fmt.Errorf("failed to marshal function call args: %w", err)
-					}
-
-					toolCalls = append(toolCalls, sdk.ToolCallRequest{
-						ID:        fmt.Sprintf("call_%d", i),
-						Name:      part.FunctionCall.Name,
-						Arguments: json.RawMessage(argsJSON),
-					})
-				}
-			}
-
-			if len(toolCalls) > 0 {
-				response := &sdk.CompletionResponse{
-					Content:   fullText,
-					ToolCalls: toolCalls,
-					Role:      "assistant",
-				}
-				responseJSON, err := json.Marshal(response)
-				if err != nil {
-					return nil, fmt.Errorf("failed to marshal completion response: %w", err)
-				}
-				return io.NopCloser(bytes.NewReader(responseJSON)), nil
-			}
-
-			if fullText == "" {
-
-				return nil, fmt.Errorf("non-streaming response body was successfully parsed but contained empty text (FinishReason: %s). Raw body: %s", candidate.FinishReason, string(body))
-			}
-
-			return io.NopCloser(bytes.NewReader([]byte(fullText))), nil
-		}
-
-		return nil, fmt.Errorf("non-streaming response body was successfully parsed but contained no candidates. Raw body: %s", string(body))
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
 	}
-
-	return resp.Body, nil
+	return result.TotalTokens, nil
 }
 
 func (p *GeminiProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
+	return p.ParseEvents(body, func(event sdk.StreamEvent) error {
+		if event.Type == sdk.StreamEventText {
+			return onChunk(event.Text)
+		}
+		return nil
+	})
+}
+
+// emits the text of the first part and the usageMetadata of each chunk
+func (p *GeminiProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
 	reader := bufio.NewReader(body)
 
 	for {
@@ -346,6 +428,11 @@ func (p *GeminiProvider) ParseResponse(body io.Reader, onChunk func(string) erro
 			var chunk GeminiResponseChunk
 
 			if jsonErr := json.Unmarshal(line, &chunk); jsonErr == nil {
+				if chunk.UsageMetadata != nil {
+					if usageErr := onEvent(sdk.StreamEvent{Type: sdk.StreamEventUsage, Usage: chunk.UsageMetadata.usage()}); usageErr != nil {
+						return usageErr
+					}
+				}
 				if len(chunk.Candidates) > 0 {
 					candidate := chunk.Candidates[0]
 
@@ -356,7 +443,7 @@ func (p *GeminiProvider) ParseResponse(body io.Reader, onChunk func(string) erro
 					if len(candidate.Content.Parts) > 0 {
 						text := candidate.Content.Parts[0].Text
 						if text != "" {
-							if chunkErr := onChunk(text); chunkErr != nil {
+							if chunkErr := onEvent(sdk.StreamEvent{Type: sdk.StreamEventText, Text: text}); chunkErr != nil {
 								return chunkErr
 							}
 						}
@@ -418,3 +505,195 @@ func convertSDKToolsToProviderTools(sdkTools map[string]sdk.Tool) *GeminiToolCon
 		FunctionDeclarations: declarations,
 	}
 }
+
+// lists models with models.list, following nextPageToken
+func (p *GeminiProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	var models []sdk.ModelInfo
+	pageToken := ""
+	for {
+		query := url.Values{"key": {p.APIKey}, "pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://generativelanguage.googleapis.com/v1beta/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := p.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Models []struct {
+				Name             string `json:"name"`
+				DisplayName      string `json:"displayName"`
+				InputTokenLimit  int    `json:"inputTokenLimit"`
+				OutputTokenLimit int    `json:"outputTokenLimit"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Models {
+			models = append(models, sdk.ModelInfo{
+				ID:            strings.TrimPrefix(m.Name, "models/"),
+				DisplayName:   m.DisplayName,
+				Provider:      p.Name(),
+				OwnedBy:       "google",
+				ContextLength: m.InputTokenLimit,
+				MaxOutput:     m.OutputTokenLimit,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// gemini task types for the SDK input types
+var geminiTaskTypes = map[sdk.InputType]string{
+	sdk.InputTypeQuery:    "RETRIEVAL_QUERY",
+	sdk.InputTypeDocument: "RETRIEVAL_DOCUMENT",
+}
+
+// embeds a batch with embedContent, or batchEmbedContents for several inputs
+// the model defaults to gemini-embedding-001
+func (p *GeminiProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = "gemini-embedding-001"
+	}
+
+	requests := make([]map[string]interface{}, 0, len(req.Input))
+	for _, text := range req.Input {
+		r := map[string]interface{}{
+			"model":   "models/" + model,
+			"content": GeminiContent{Parts: []GeminiPart{{Text: text}}},
+		}
+		if taskType, ok := geminiTaskTypes[req.InputType]; ok {
+			r["taskType"] = taskType
+		}
+		if req.Dimensions > 0 {
+			r["outputDimensionality"] = req.Dimensions
+		}
+		requests = append(requests, r)
+	}
+
+	var body interface{} = map[string]interface{}{"requests": requests}
+	method := "batchEmbedContents"
+	if len(requests) == 1 {
+		body = requests[0]
+		method = "embedContent"
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:%s?key=%s", model, method, p.APIKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	type embedding struct {
+		Values []float32 `json:"values"`
+	}
+	var parsed struct {
+		Embedding  *embedding  `json:"embedding"`
+		Embeddings []embedding `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if parsed.Embedding != nil {
+		parsed.Embeddings = append(parsed.Embeddings, *parsed.Embedding)
+	}
+
+	result := &sdk.EmbedResponse{Model: model}
+	for _, e := range parsed.Embeddings {
+		result.Embeddings = append(result.Embeddings, e.Values)
+	}
+	return result, nil
+}
+
+func (p *GeminiProvider) MaxEmbedInputs() int {
+	return 100
+}
+
+// generates images with generateContent and image output parts, one call per image
+// the model defaults to gemini-2.5-flash-image, size, quality and URL responses are not supported
+func (p *GeminiProvider) GenerateImage(ctx context.Context, req *sdk.ImageRequest) (*sdk.ImageResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = "gemini-2.5-flash-image"
+	}
+
+	jsonBody, err := json.Marshal(GeminiRequest{
+		Contents:         []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: req.Prompt}}}},
+		GenerationConfig: &GenerationConfig{ResponseModalities: []string{"TEXT", "IMAGE"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &sdk.ImageResponse{Model: model}
+	for range max(req.N, 1) {
+		url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, p.APIKey)
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+
+		resp, err := p.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		var parsed GeminiResponse
+		err = json.NewDecoder(resp.Body).Decode(&parsed)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if parsed.PromptFeedback != nil && parsed.PromptFeedback.BlockReason != "" {
+			return nil, &ContentBlockedError{Reason: parsed.PromptFeedback.BlockReason}
+		}
+		for _, candidate := range parsed.Candidates {
+			for _, part := range candidate.Content.Parts {
+				if part.InlineData == nil {
+					continue
+				}
+				data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				if err != nil {
+					return nil, err
+				}
+				result.Images = append(result.Images, sdk.Image{Data: data, MIMEType: part.InlineData.MimeType})
+			}
+		}
+		result.Usage = result.Usage.Add(sdk.Usage{
+			InputTokens:  parsed.UsageMetadata.PromptTokenCount,
+			OutputTokens: parsed.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:  parsed.UsageMetadata.TotalTokenCount,
+		})
+	}
+	return result, nil
+}
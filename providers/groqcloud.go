@@ -26,6 +26,10 @@ func NewGroqCloudProvider(apiKey string) *GroqCloudProvider {
 	return p
 }
 
+func (p *GroqCloudProvider) Name() string {
+	return "groq"
+}
+
 func (p *GroqCloudProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.groq.com/openai/v1/chat/completions"
 
@@ -68,23 +72,32 @@ func (p *GroqCloudProvider) CallAPI(ctx context.Context, messages []sdk.Message,
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
-	}
-
 	return resp.Body, nil
 }
+
+// lists models with GET /openai/v1/models
+func (p *GroqCloudProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://api.groq.com/openai/v1/models", p.Name(), http.Header{"Authorization": {"Bearer " + p.APIKey}})
+}
+
+// transcribes audio with POST /openai/v1/audio/transcriptions, the model defaults to whisper-large-v3-turbo
+func (p *GroqCloudProvider) Transcribe(ctx context.Context, req *sdk.TranscriptionRequest) (*sdk.Transcription, error) {
+	model := req.Model
+	if model == "" {
+		model = "whisper-large-v3-turbo"
+	}
+	return p.TranscribeOpenAI(ctx, "https://api.groq.com/openai/v1/audio/transcriptions", http.Header{"Authorization": {"Bearer " + p.APIKey}}, model, req)
+}
+
 func (p *GroqCloudProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *GroqCloudProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
@@ -26,6 +26,10 @@ func NewMistralProvider(apiKey string) *MistralProvider {
 	return p
 }
 
+func (p *MistralProvider) Name() string {
+	return "mistral"
+}
+
 func (p *MistralProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.mistral.ai/v1/chat/completions"
 
@@ -64,24 +68,59 @@ func (p *MistralProvider) CallAPI(ctx context.Context, messages []sdk.Message, s
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
+	return resp.Body, nil
+}
+
+// lists models with GET /v1/models
+func (p *MistralProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://api.mistral.ai/v1/models", p.Name(), http.Header{"Authorization": {"Bearer " + p.APIKey}})
+}
+
+// embeds a batch with POST /v1/embeddings, the model defaults to mistral-embed
+func (p *MistralProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	r := *req
+	if r.Model == "" {
+		r.Model = "mistral-embed"
+	}
+	// mistral names the dimensions parameter output_dimension
+	var extra map[string]interface{}
+	if r.Dimensions > 0 {
+		extra = map[string]interface{}{"output_dimension": r.Dimensions}
+		r.Dimensions = 0
 	}
+	return p.EmbedOpenAI(ctx, "https://api.mistral.ai/v1/embeddings", http.Header{"Authorization": {"Bearer " + p.APIKey}}, &r, extra)
+}
 
-	return resp.Body, nil
+func (p *MistralProvider) MaxEmbedInputs() int {
+	return 128
+}
+
+var mistralModerationCategories = map[string]sdk.ModerationCategory{
+	"sexual":                         sdk.ModerationSexual,
+	"hate_and_discrimination":        sdk.ModerationHate,
+	"violence_and_threats":           sdk.ModerationViolence,
+	"dangerous_and_criminal_content": sdk.ModerationIllicit,
+	"selfharm":                       sdk.ModerationSelfHarm,
+	"health":                         sdk.ModerationHealth,
+	"financial":                      sdk.ModerationFinancial,
+	"law":                            sdk.ModerationLaw,
+	"pii":                            sdk.ModerationPII,
+}
+
+// classifies inputs with POST /v1/moderations and mistral-moderation-latest
+func (p *MistralProvider) Moderate(ctx context.Context, inputs []string) ([]sdk.ModerationResult, error) {
+	return p.ModerateOpenAI(ctx, "https://api.mistral.ai/v1/moderations", http.Header{"Authorization": {"Bearer " + p.APIKey}}, "mistral-moderation-latest", inputs, mistralModerationCategories)
 }
 
 func (p *MistralProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *MistralProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
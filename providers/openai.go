@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/xerohard/ai/v2/base"
 	"github.com/xerohard/ai/v2/sdk"
@@ -15,7 +16,8 @@ import (
 
 type OpenAiProvider struct {
 	*base.Provider
-	APIKey string
+	APIKey       string
+	Organization string // optional, sent as OpenAI-Organization
 }
 
 func NewOpenAiProvider(apiKey string) *OpenAiProvider {
@@ -26,9 +28,46 @@ func NewOpenAiProvider(apiKey string) *OpenAiProvider {
 	return p
 }
 
+func (p *OpenAiProvider) Name() string {
+	return "openai"
+}
+
 func (p *OpenAiProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.openai.com/v1/chat/completions"
 
+	body := p.buildRequest(messages, opts)
+	body["stream"] = streamMode
+	if streamMode {
+		// the usage is sent in a last chunk without choices
+		body["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	if p.Organization != "" {
+		req.Header.Set("OpenAI-Organization", p.Organization)
+	}
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// chat completions request body, shared by CallAPI and batches
+func (p *OpenAiProvider) buildRequest(messages []sdk.Message, opts *sdk.Options) map[string]interface{} {
 	chatMessages := []map[string]string{}
 	for _, m := range messages {
 		chatMessages = append(chatMessages, map[string]string{
@@ -39,7 +78,6 @@ func (p *OpenAiProvider) CallAPI(ctx context.Context, messages []sdk.Message, st
 
 	body := map[string]interface{}{
 		"messages": chatMessages,
-		"stream":   streamMode,
 	}
 	if opts != nil {
 		if opts.Model != "" {
@@ -52,37 +90,125 @@ func (p *OpenAiProvider) CallAPI(ctx context.Context, messages []sdk.Message, st
 			body["reasoning_effort"] = opts.ReasoningEffort
 		}
 	}
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
+	return body
+}
+
+// lists models with GET /v1/models
+func (p *OpenAiProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://api.openai.com/v1/models", p.Name(), p.headers())
+}
+
+// embeds a batch with POST /v1/embeddings, the model defaults to text-embedding-3-small
+func (p *OpenAiProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	if req.Model == "" {
+		r := *req
+		r.Model = "text-embedding-3-small"
+		req = &r
 	}
+	return p.EmbedOpenAI(ctx, "https://api.openai.com/v1/embeddings", p.headers(), req, nil)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, err
+func (p *OpenAiProvider) MaxEmbedInputs() int {
+	return 2048
+}
+
+// generates images with POST /v1/images/generations, the model defaults to gpt-image-1
+func (p *OpenAiProvider) GenerateImage(ctx context.Context, req *sdk.ImageRequest) (*sdk.ImageResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = "gpt-image-1"
 	}
 
-	req.Header.Set("Authorization", "Bearer "+p.APIKey)
-	req.Header.Set("Content-Type", "application/json")
+	body := map[string]interface{}{
+		"model":  model,
+		"prompt": req.Prompt,
+	}
+	if req.N > 0 {
+		body["n"] = req.N
+	}
+	if req.Size != "" {
+		body["size"] = req.Size
+	}
+	if req.Quality != "" {
+		body["quality"] = req.Quality
+	}
+	// gpt-image models always return base64 and reject response_format
+	if req.ResponseFormat != "" && !strings.HasPrefix(model, "gpt-image") {
+		body["response_format"] = req.ResponseFormat
+	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	return p.GenerateOpenAIImage(ctx, "https://api.openai.com/v1/images/generations", p.headers(), model, body)
+}
+
+// transcribes audio with POST /v1/audio/transcriptions, the model defaults to whisper-1
+// timestamps are only supported by whisper-1
+func (p *OpenAiProvider) Transcribe(ctx context.Context, req *sdk.TranscriptionRequest) (*sdk.Transcription, error) {
+	model := req.Model
+	if model == "" {
+		model = "whisper-1"
 	}
+	return p.TranscribeOpenAI(ctx, "https://api.openai.com/v1/audio/transcriptions", p.headers(), model, req)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
+// synthesizes speech with POST /v1/audio/speech, defaulting to gpt-4o-mini-tts and the alloy voice
+func (p *OpenAiProvider) Speak(ctx context.Context, req *sdk.SpeechRequest) (io.ReadCloser, error) {
+	body := map[string]interface{}{
+		"model": "gpt-4o-mini-tts",
+		"input": req.Input,
+		"voice": "alloy",
+	}
+	if req.Model != "" {
+		body["model"] = req.Model
+	}
+	if req.Voice != "" {
+		body["voice"] = req.Voice
+	}
+	if req.Format != "" {
+		body["response_format"] = req.Format
+	}
+	if req.Speed != 0 {
+		body["speed"] = req.Speed
+	}
+	if req.Instructions != "" {
+		body["instructions"] = req.Instructions
+	}
+	return p.SpeakOpenAI(ctx, "https://api.openai.com/v1/audio/speech", p.headers(), body)
+}
+
+// authentication headers for the non chat endpoints
+func (p *OpenAiProvider) headers() http.Header {
+	header := http.Header{"Authorization": {"Bearer " + p.APIKey}}
+	if p.Organization != "" {
+		header.Set("OpenAI-Organization", p.Organization)
 	}
+	return header
+}
 
-	return resp.Body, nil
+var openAIModerationCategories = map[string]sdk.ModerationCategory{
+	"sexual":                 sdk.ModerationSexual,
+	"sexual/minors":          sdk.ModerationSexualMinors,
+	"hate":                   sdk.ModerationHate,
+	"hate/threatening":       sdk.ModerationHate,
+	"harassment":             sdk.ModerationHarassment,
+	"harassment/threatening": sdk.ModerationHarassment,
+	"self-harm":              sdk.ModerationSelfHarm,
+	"self-harm/intent":       sdk.ModerationSelfHarm,
+	"self-harm/instructions": sdk.ModerationSelfHarm,
+	"violence":               sdk.ModerationViolence,
+	"violence/graphic":       sdk.ModerationViolence,
+	"illicit":                sdk.ModerationIllicit,
+	"illicit/violent":        sdk.ModerationIllicit,
+}
+
+// classifies inputs with POST /v1/moderations and omni-moderation-latest
+func (p *OpenAiProvider) Moderate(ctx context.Context, inputs []string) ([]sdk.ModerationResult, error) {
+	return p.ModerateOpenAI(ctx, "https://api.openai.com/v1/moderations", p.headers(), "omni-moderation-latest", inputs, openAIModerationCategories)
 }
 
 func (p *OpenAiProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *OpenAiProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
@@ -0,0 +1,259 @@
+// OpenAI Batch API
+
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/xerohard/ai/v2/base"
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+type openAIBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	CreatedAt     int64  `json:"created_at"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+var openAIBatchStatuses = map[string]sdk.BatchStatus{
+	"completed":  sdk.BatchCompleted,
+	"failed":     sdk.BatchFailed,
+	"expired":    sdk.BatchExpired,
+	"cancelled":  sdk.BatchCancelled,
+	"cancelling": sdk.BatchCancelling,
+}
+
+func (b *openAIBatch) toBatch() *sdk.Batch {
+	status, ok := openAIBatchStatuses[b.Status]
+	if !ok {
+		status = sdk.BatchInProgress // validating, in_progress, finalizing
+	}
+	batch := &sdk.Batch{
+		ID:             b.ID,
+		Status:         status,
+		ProviderStatus: b.Status,
+		Total:          b.RequestCounts.Total,
+		Completed:      b.RequestCounts.Completed,
+		Failed:         b.RequestCounts.Failed,
+		CreatedAt:      time.Unix(b.CreatedAt, 0),
+	}
+	for _, id := range []string{b.OutputFileID, b.ErrorFileID} {
+		if id != "" {
+			batch.Results = append(batch.Results, id)
+		}
+	}
+	return batch
+}
+
+// uploads the requests as a JSONL file and creates a /v1/chat/completions batch
+func (p *OpenAiProvider) SubmitBatch(ctx context.Context, items []sdk.BatchItem) (*sdk.Batch, error) {
+	var jsonl bytes.Buffer
+	enc := json.NewEncoder(&jsonl)
+	for _, item := range items {
+		messages := p.AddSystemPrompt(item.Messages, item.Options)
+		err := enc.Encode(map[string]interface{}{
+			"custom_id": item.CustomID,
+			"method":    "POST",
+			"url":       "/v1/chat/completions",
+			"body":      p.buildRequest(messages, item.Options),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileID, err := p.uploadBatchFile(ctx, jsonl.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("uploading batch file: %w", err)
+	}
+
+	var batch openAIBatch
+	err = p.batchRequest(ctx, "POST", "https://api.openai.com/v1/batches", map[string]interface{}{
+		"input_file_id":     fileID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	}, &batch)
+	if err != nil {
+		return nil, err
+	}
+	return batch.toBatch(), nil
+}
+
+func (p *OpenAiProvider) GetBatch(ctx context.Context, id string) (*sdk.Batch, error) {
+	var batch openAIBatch
+	if err := p.batchRequest(ctx, "GET", "https://api.openai.com/v1/batches/"+id, nil, &batch); err != nil {
+		return nil, err
+	}
+	return batch.toBatch(), nil
+}
+
+func (p *OpenAiProvider) CancelBatch(ctx context.Context, id string) error {
+	return p.batchRequest(ctx, "POST", "https://api.openai.com/v1/batches/"+id+"/cancel", nil, nil)
+}
+
+// reads the output and error files of the batch
+func (p *OpenAiProvider) BatchResults(ctx context.Context, batch *sdk.Batch, fn func(sdk.BatchResult) error) error {
+	if len(batch.Results) == 0 {
+		var err error
+		if batch, err = p.GetBatch(ctx, batch.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, fileID := range batch.Results {
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://api.openai.com/v1/files/"+fileID+"/content", nil)
+		if err != nil {
+			return err
+		}
+		for name, values := range p.headers() {
+			req.Header[name] = values
+		}
+
+		resp, err := p.Do(req)
+		if err != nil {
+			return err
+		}
+		err = readJSONLines(resp.Body, func(line []byte) error {
+			var parsed struct {
+				CustomID string `json:"custom_id"`
+				Response *struct {
+					StatusCode int             `json:"status_code"`
+					Body       json.RawMessage `json:"body"`
+				} `json:"response"`
+				Error *struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(line, &parsed); err != nil {
+				return err
+			}
+
+			result := sdk.BatchResult{CustomID: parsed.CustomID}
+			switch {
+			case parsed.Error != nil:
+				result.Err = fmt.Errorf("%s: %s", parsed.Error.Code, parsed.Error.Message)
+			case parsed.Response == nil:
+				result.Err = errors.New("missing response")
+			case parsed.Response.StatusCode != http.StatusOK:
+				result.Err = &sdk.APIError{StatusCode: parsed.Response.StatusCode, Message: string(parsed.Response.Body), Body: parsed.Response.Body}
+			default:
+				result.Response, result.Err = base.ExtractJsonResponse(parsed.Response.Body)
+			}
+			return fn(result)
+		})
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *OpenAiProvider) uploadBatchFile(ctx context.Context, content []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("purpose", "batch"); err != nil {
+		return "", err
+	}
+	file, err := form.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	file.Write(content)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/files", &body)
+	if err != nil {
+		return "", err
+	}
+	for name, values := range p.headers() {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return "", err
+	}
+	return uploaded.ID, nil
+}
+
+// sends a JSON request to the batches endpoints and decodes the response into out when not nil
+func (p *OpenAiProvider) batchRequest(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		jsonBody, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range p.headers() {
+		req.Header[name] = values
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// calls fn for every non empty line of a JSONL stream
+func readJSONLines(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -26,6 +26,10 @@ func NewOpenRouterProvider(apiKey string) *OpenRouterProvider {
 	return p
 }
 
+func (p *OpenRouterProvider) Name() string {
+	return "openrouter"
+}
+
 func (p *OpenRouterProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://openrouter.ai/api/v1/chat/completions"
 
@@ -72,24 +76,32 @@ func (p *OpenRouterProvider) CallAPI(ctx context.Context, messages []sdk.Message
 	req.Header.Set("HTTP-Referer", "https://github.com/xerohard/ai/v2")
 	req.Header.Set("X-Title", "unsafe0x0/ai")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
-	}
-
 	return resp.Body, nil
 }
 
+// lists models with GET /api/v1/models
+func (p *OpenRouterProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://openrouter.ai/api/v1/models", p.Name(), http.Header{"Authorization": {"Bearer " + p.APIKey}})
+}
+
+// embeds a batch with POST /api/v1/embeddings
+func (p *OpenRouterProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	return p.EmbedOpenAI(ctx, "https://openrouter.ai/api/v1/embeddings", http.Header{"Authorization": {"Bearer " + p.APIKey}}, req, nil)
+}
+
+func (p *OpenRouterProvider) MaxEmbedInputs() int {
+	return 512
+}
+
 func (p *OpenRouterProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *OpenRouterProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
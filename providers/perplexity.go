@@ -26,6 +26,10 @@ func NewPerplexityProvider(apiKey string) *PerplexityProvider {
 	return p
 }
 
+func (p *PerplexityProvider) Name() string {
+	return "perplexity"
+}
+
 func (p *PerplexityProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.perplexity.ai/chat/completions"
 
@@ -54,6 +58,17 @@ func (p *PerplexityProvider) CallAPI(ctx context.Context, messages []sdk.Message
 		if opts.Temperature != 0 {
 			body["temperature"] = opts.Temperature
 		}
+		if search := opts.Search; search != nil {
+			if len(search.DomainFilter) > 0 {
+				body["search_domain_filter"] = search.DomainFilter
+			}
+			if search.RecencyFilter != "" {
+				body["search_recency_filter"] = search.RecencyFilter
+			}
+			if search.ReturnImages {
+				body["return_images"] = true
+			}
+		}
 	}
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -68,24 +83,87 @@ func (p *PerplexityProvider) CallAPI(ctx context.Context, messages []sdk.Message
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
+	return resp.Body, nil
+}
+
+// search results returned next to the completion, in every streamed chunk as well
+type perplexitySearch struct {
+	Citations     []string `json:"citations"`
+	SearchResults []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Date    string `json:"date"`
+		Snippet string `json:"snippet"`
+	} `json:"search_results"`
+	Images []struct {
+		ImageURL  string `json:"image_url"`
+		OriginURL string `json:"origin_url"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"images"`
+}
+
+// search results are preferred, the older citations field only holds URLs
+func (s *perplexitySearch) citations() []sdk.Citation {
+	var citations []sdk.Citation
+	if len(s.SearchResults) > 0 {
+		for _, r := range s.SearchResults {
+			citations = append(citations, sdk.Citation{URL: r.URL, Title: r.Title, Snippet: r.Snippet, Date: r.Date})
 		}
+		return citations
+	}
+	for _, url := range s.Citations {
+		citations = append(citations, sdk.Citation{URL: url})
 	}
+	return citations
+}
 
-	return resp.Body, nil
+func (s *perplexitySearch) images() []sdk.SearchImage {
+	var images []sdk.SearchImage
+	for _, img := range s.Images {
+		images = append(images, sdk.SearchImage{URL: img.ImageURL, OriginURL: img.OriginURL, Width: img.Width, Height: img.Height})
+	}
+	return images
+}
+
+func (p *PerplexityProvider) ExtractResponse(body []byte) (*sdk.CompletionResponse, error) {
+	resp, err := base.ExtractJsonResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var search perplexitySearch
+	if err := json.Unmarshal(body, &search); err != nil {
+		return nil, err
+	}
+	resp.Citations = search.citations()
+	resp.Images = search.images()
+	return resp, nil
 }
 
 func (p *PerplexityProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+// emits a citations event whenever a chunk carries more citations or images than seen so far
+func (p *PerplexityProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	citations, images := 0, 0
+	return base.ReadEventData(body, func(data []byte) error {
+		var search perplexitySearch
+		if err := json.Unmarshal(data, &search); err == nil {
+			event := sdk.StreamEvent{Type: sdk.StreamEventCitations, Citations: search.citations(), Images: search.images()}
+			if len(event.Citations) > citations || len(event.Images) > images {
+				citations, images = len(event.Citations), len(event.Images)
+				if err := onEvent(event); err != nil {
+					return err
+				}
+			}
+		}
+		return base.JsonChunkEvents(data, onEvent)
+	})
+}
@@ -0,0 +1,137 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xerohard/ai/v2/base"
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newTestPerplexity(rec *recorder, body string) *PerplexityProvider {
+	p := NewPerplexityProvider("test-key")
+	p.Provider = &base.Provider{APICaller: p, HTTPClient: rec.client(http.StatusOK, body)}
+	return p
+}
+
+var perplexityCitations = []sdk.Citation{
+	{URL: "https://example.com/coffee", Title: "Coffee prices", Snippet: "A cup costs 5 €.", Date: "2025-01-02"},
+	{URL: "https://example.com/zurich", Title: "Zürich guide", Snippet: "Cafés in Zürich."},
+}
+
+func TestPerplexitySearchResults(t *testing.T) {
+	rec := &recorder{}
+	p := newTestPerplexity(rec, fixture(t, "perplexity_response.json"))
+
+	opts := &sdk.Options{Model: "sonar", Search: &sdk.SearchOptions{ReturnImages: true}}
+	resp, err := p.CreateCompletion(context.Background(), []sdk.Message{{Role: "user", Content: "coffee prices?"}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.body["return_images"] != true {
+		t.Errorf("return_images = %v", rec.body["return_images"])
+	}
+	if resp.Content != "A cup costs 5 € [1]." {
+		t.Errorf("content = %q", resp.Content)
+	}
+	if !reflect.DeepEqual(resp.Citations, perplexityCitations) {
+		t.Errorf("citations = %+v", resp.Citations)
+	}
+	wantImages := []sdk.SearchImage{{URL: "https://example.com/cup.jpg", OriginURL: "https://example.com/coffee", Width: 640, Height: 480}}
+	if !reflect.DeepEqual(resp.Images, wantImages) {
+		t.Errorf("images = %+v", resp.Images)
+	}
+}
+
+func TestPerplexityCitationsFallback(t *testing.T) {
+	p := newTestPerplexity(&recorder{}, fixture(t, "perplexity_citations_only.json"))
+
+	resp, err := p.CreateCompletion(context.Background(), []sdk.Message{{Role: "user", Content: "coffee prices?"}}, &sdk.Options{Model: "sonar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []sdk.Citation{{URL: "https://example.com/coffee"}, {URL: "https://example.com/zurich"}}
+	if !reflect.DeepEqual(resp.Citations, want) {
+		t.Errorf("citations = %+v", resp.Citations)
+	}
+	if resp.Images != nil {
+		t.Errorf("images = %+v", resp.Images)
+	}
+}
+
+func TestPerplexityStreamCitations(t *testing.T) {
+	p := newTestPerplexity(&recorder{}, fixture(t, "perplexity_stream.txt"))
+
+	var events []sdk.StreamEvent
+	opts := &sdk.Options{Model: "sonar", OnStreamEvent: func(event sdk.StreamEvent) {
+		if event.Type == sdk.StreamEventCitations {
+			events = append(events, event)
+		}
+	}}
+	stream, err := p.CreateCompletionStream(context.Background(), []sdk.Message{{Role: "user", Content: "coffee prices?"}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := io.ReadAll(stream)
+	stream.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(text) != "A cup costs 5 € [1]." {
+		t.Errorf("text = %q", text)
+	}
+	// every chunk repeats the search results, only the first one and the one adding images are reported
+	if len(events) != 2 {
+		t.Fatalf("got %d citations events, want 2", len(events))
+	}
+	for i, event := range events {
+		if !reflect.DeepEqual(event.Citations, perplexityCitations) {
+			t.Errorf("event %d citations = %+v", i, event.Citations)
+		}
+	}
+	if len(events[0].Images) != 0 || len(events[1].Images) != 1 {
+		t.Errorf("images = %d, %d, want 0, 1", len(events[0].Images), len(events[1].Images))
+	}
+}
+
+func TestURLCitationByteOffsets(t *testing.T) {
+	p := NewOpenAiProvider("test-key")
+	p.HTTPClient = (&recorder{}).client(http.StatusOK, fixture(t, "openai_url_citation.json"))
+
+	resp, err := p.CreateCompletion(context.Background(), []sdk.Message{{Role: "user", Content: "coffee in Zürich?"}}, &sdk.Options{Model: "gpt-4o-search-preview"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Citations) != 1 {
+		t.Fatalf("got %d citations", len(resp.Citations))
+	}
+	c := resp.Citations[0]
+	// "é" and "ü" are two bytes and "€" three, so the character indexes 23 and 34 move by 2 and 4
+	if c.Start != 25 || c.End != 38 {
+		t.Errorf("offsets = %d, %d, want 25, 38", c.Start, c.End)
+	}
+	if got := resp.Content[c.Start:c.End]; got != "5 € per cup" {
+		t.Errorf("cited span = %q", got)
+	}
+	if c.URL != "https://example.com/coffee" || c.Title != "Coffee prices" {
+		t.Errorf("citation = %+v", c)
+	}
+}
@@ -26,6 +26,10 @@ func NewXaiProvider(apiKey string) *XaiProvider {
 	return p
 }
 
+func (p *XaiProvider) Name() string {
+	return "xai"
+}
+
 func (p *XaiProvider) CallAPI(ctx context.Context, messages []sdk.Message, streamMode bool, opts *sdk.Options) (io.ReadCloser, error) {
 	url := "https://api.x.ai/v1/chat/completions"
 
@@ -68,24 +72,54 @@ func (p *XaiProvider) CallAPI(ctx context.Context, messages []sdk.Message, strea
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, &sdk.APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(b),
-			Body:       b,
-		}
+	return resp.Body, nil
+}
+
+// lists models with GET /v1/models
+func (p *XaiProvider) ListModels(ctx context.Context) ([]sdk.ModelInfo, error) {
+	return p.ListOpenAIModels(ctx, "https://api.x.ai/v1/models", p.Name(), http.Header{"Authorization": {"Bearer " + p.APIKey}})
+}
+
+// embeds a batch with POST /v1/embeddings
+func (p *XaiProvider) Embed(ctx context.Context, req *sdk.EmbedRequest) (*sdk.EmbedResponse, error) {
+	return p.EmbedOpenAI(ctx, "https://api.x.ai/v1/embeddings", http.Header{"Authorization": {"Bearer " + p.APIKey}}, req, nil)
+}
+
+func (p *XaiProvider) MaxEmbedInputs() int {
+	return 512
+}
+
+// generates images with POST /v1/images/generations, the model defaults to grok-2-image
+// size and quality are not supported by xAI
+func (p *XaiProvider) GenerateImage(ctx context.Context, req *sdk.ImageRequest) (*sdk.ImageResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = "grok-2-image"
 	}
 
-	return resp.Body, nil
+	body := map[string]interface{}{
+		"model":  model,
+		"prompt": req.Prompt,
+	}
+	if req.N > 0 {
+		body["n"] = req.N
+	}
+	if req.ResponseFormat != "" {
+		body["response_format"] = req.ResponseFormat
+	}
+
+	return p.GenerateOpenAIImage(ctx, "https://api.x.ai/v1/images/generations", http.Header{"Authorization": {"Bearer " + p.APIKey}}, model, body)
 }
 
 func (p *XaiProvider) ParseResponse(body io.Reader, onChunk func(string) error) error {
 	return base.ParseJsonStream(body, onChunk)
 }
+
+func (p *XaiProvider) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
@@ -0,0 +1,80 @@
+// speech to text and text to speech
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type TranscriptionRequest struct {
+	Model          string    // defaults to the provider's transcription model
+	Audio          io.Reader // audio file contents
+	Filename       string    // used by the provider to detect the format, e.g. "meeting.mp3"
+	Language       string    // ISO-639-1 code, optional
+	Prompt         string    // optional context or spelling hints
+	Temperature    float32
+	Timestamps     bool // requests verbose JSON with segment timestamps
+	WordTimestamps bool // also requests word timestamps
+}
+
+type TranscriptionSegment struct {
+	ID    int
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type TranscriptionWord struct {
+	Word  string
+	Start time.Duration
+	End   time.Duration
+}
+
+type Transcription struct {
+	Text     string
+	Language string
+	Duration time.Duration
+	Segments []TranscriptionSegment // only with Timestamps
+	Words    []TranscriptionWord    // only with WordTimestamps
+}
+
+type SpeechRequest struct {
+	Model        string  // defaults to the provider's speech model
+	Input        string  // text to speak
+	Voice        string  // defaults to the provider's default voice
+	Format       string  // e.g. "mp3", "opus", "wav", "pcm"
+	Speed        float64 // optional
+	Instructions string  // tone or style instructions, for models supporting them
+}
+
+// implemented by providers with a transcription endpoint
+type Transcriber interface {
+	Transcribe(ctx context.Context, req *TranscriptionRequest) (*Transcription, error)
+}
+
+// implemented by providers with a text to speech endpoint
+type Speaker interface {
+	Speak(ctx context.Context, req *SpeechRequest) (io.ReadCloser, error)
+}
+
+// transcribes audio
+// returns ErrUnsupported when the provider has no transcription endpoint
+func (sdk *SDK) Transcribe(ctx context.Context, req *TranscriptionRequest) (*Transcription, error) {
+	transcriber, ok := capability[Transcriber](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return transcriber.Transcribe(ctx, req)
+}
+
+// returns the audio as it is streamed by the provider, the caller must close it
+// returns ErrUnsupported when the provider has no text to speech endpoint
+func (sdk *SDK) Speak(ctx context.Context, req *SpeechRequest) (io.ReadCloser, error) {
+	speaker, ok := capability[Speaker](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return speaker.Speak(ctx, req)
+}
@@ -0,0 +1,158 @@
+// asynchronous batch completions
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type BatchStatus string
+
+const (
+	BatchInProgress BatchStatus = "in_progress" // validating, queued or running
+	BatchCancelling BatchStatus = "cancelling"  // cancel requested, finished requests are kept
+	BatchCompleted  BatchStatus = "completed"
+	BatchFailed     BatchStatus = "failed"
+	BatchCancelled  BatchStatus = "cancelled"
+	BatchExpired    BatchStatus = "expired"
+)
+
+// reports whether the batch reached a final status
+func (s BatchStatus) Done() bool {
+	return s != BatchInProgress && s != BatchCancelling
+}
+
+// one request of a batch, Messages are the request messages as is
+// providers add Options.SystemPrompt with AddSystemPrompt when building the batch
+type BatchItem struct {
+	CustomID string
+	Messages []Message
+	Options  *Options
+}
+
+type Batch struct {
+	ID             string
+	Status         BatchStatus
+	ProviderStatus string // status as reported by the provider
+	Total          int
+	Completed      int
+	Failed         int
+	CreatedAt      time.Time
+	Results        []string // provider specific result locations (file IDs or URLs), set once done
+}
+
+// result of one batch item, Err is set for items the provider failed
+type BatchResult struct {
+	CustomID string
+	Response *CompletionResponse
+	Err      error
+}
+
+// implemented by providers with an asynchronous batch API
+type BatchProvider interface {
+	SubmitBatch(ctx context.Context, items []BatchItem) (*Batch, error)
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+	CancelBatch(ctx context.Context, id string) error
+	// calls fn for every result as it is read, stops at the first error returned by fn
+	BatchResults(ctx context.Context, batch *Batch, fn func(BatchResult) error) error
+}
+
+// custom ID of the request at index i in SubmitBatch
+func BatchCustomID(i int) string {
+	return fmt.Sprintf("request-%d", i)
+}
+
+func (sdk *SDK) batchProvider() (BatchProvider, error) {
+	provider, ok := capability[BatchProvider](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return provider, nil
+}
+
+// submits the requests as one batch, using the same request conversion as ChatCompletion
+// results are keyed by BatchCustomID(index), tool calls are returned but not executed
+func (sdk *SDK) SubmitBatch(ctx context.Context, reqs []CompletionRequest) (*Batch, error) {
+	provider, err := sdk.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = sdk.withLogger(ctx)
+	items := make([]BatchItem, len(reqs))
+	for i := range reqs {
+		opts := reqs[i].options()
+		if err := sdk.validate(ctx, reqs[i].Messages, opts); err != nil {
+			return nil, fmt.Errorf("%s: %w", BatchCustomID(i), err)
+		}
+		items[i] = BatchItem{CustomID: BatchCustomID(i), Messages: reqs[i].Messages, Options: opts}
+	}
+
+	batch, err := provider.SubmitBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	Logger(ctx).Info("batch submitted", "batch", batch.ID, "requests", len(items))
+	return batch, nil
+}
+
+func (sdk *SDK) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	provider, err := sdk.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	return provider.GetBatch(ctx, id)
+}
+
+func (sdk *SDK) CancelBatch(ctx context.Context, id string) error {
+	provider, err := sdk.batchProvider()
+	if err != nil {
+		return err
+	}
+	return provider.CancelBatch(ctx, id)
+}
+
+// polls the batch until it is done, starting at interval and backing off up to maxBatchPoll
+func (sdk *SDK) WaitBatch(ctx context.Context, id string, interval time.Duration) (*Batch, error) {
+	provider, err := sdk.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ctx = sdk.withLogger(ctx)
+	for {
+		batch, err := provider.GetBatch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Status.Done() {
+			return batch, nil
+		}
+		Logger(ctx).Debug("batch in progress", "batch", id, "completed", batch.Completed, "failed", batch.Failed, "total", batch.Total)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return batch, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxBatchPoll)
+	}
+}
+
+const maxBatchPoll = 5 * time.Minute
+
+// streams the results of a finished batch
+func (sdk *SDK) BatchResults(ctx context.Context, batch *Batch, fn func(BatchResult) error) error {
+	provider, err := sdk.batchProvider()
+	if err != nil {
+		return err
+	}
+	return provider.BatchResults(ctx, batch, fn)
+}
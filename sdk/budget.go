@@ -0,0 +1,184 @@
+// token, cost and time budgets for completions
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// limits for the whole tool loop of a request, zero values are unlimited
+// a Budget accumulates what is spent across the requests it is used for,
+// so sharing one (e.g. in Conversation defaults) gives a per session budget
+// streamed completions are charged when the stream ends, with the usage reported by the provider
+type Budget struct {
+	MaxTokens   int           // total input and output tokens
+	MaxCost     float64       // USD
+	Prices      *PriceTable   // prices for MaxCost, defaults to the SDK pricing
+	MaxDuration time.Duration // wall time spent in requests
+
+	mu       sync.Mutex
+	tokens   int
+	cost     float64
+	duration time.Duration
+}
+
+// returns what has been spent so far
+func (b *Budget) Spent() (tokens int, cost float64, duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens, b.cost, b.duration
+}
+
+// clears what has been spent
+func (b *Budget) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens, b.cost, b.duration = 0, 0, 0
+}
+
+// returned when a request stops because a budget limit was reached
+// Messages holds the transcript produced before stopping
+type BudgetExceededError struct {
+	Limit    string // "tokens", "cost" or "duration"
+	Max      float64
+	Spent    float64
+	Messages []Message
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: %s %g of %g", e.Limit, e.Spent, e.Max)
+}
+
+var errDurationBudget = errors.New("duration budget exceeded")
+
+// charges a completion and reports the first limit it exceeds
+func (b *Budget) charge(resp *CompletionResponse, cost float64) *BudgetExceededError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens := resp.Usage.TotalTokens
+	if tokens == 0 {
+		tokens = resp.Usage.InputTokens + resp.Usage.OutputTokens
+	}
+	b.tokens += tokens
+	b.cost += cost
+	return b.exceeded()
+}
+
+// reports whether a limit is already reached, used before starting a request
+func (b *Budget) check() *BudgetExceededError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.MaxDuration > 0 && b.duration >= b.MaxDuration {
+		return &BudgetExceededError{Limit: "duration", Max: b.MaxDuration.Seconds(), Spent: b.duration.Seconds()}
+	}
+	return b.exceeded()
+}
+
+func (b *Budget) exceeded() *BudgetExceededError {
+	switch {
+	case b.MaxTokens > 0 && b.tokens >= b.MaxTokens:
+		return &BudgetExceededError{Limit: "tokens", Max: float64(b.MaxTokens), Spent: float64(b.tokens)}
+	case b.MaxCost > 0 && b.cost >= b.MaxCost:
+		return &BudgetExceededError{Limit: "cost", Max: b.MaxCost, Spent: b.cost}
+	}
+	return nil
+}
+
+// starts timing a request, the returned context expires when the duration budget runs out
+// stop records the time spent and must be called once the request is done
+func (b *Budget) start(ctx context.Context) (context.Context, func()) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+
+	b.mu.Lock()
+	if b.MaxDuration > 0 {
+		ctx, cancel = context.WithDeadlineCause(ctx, start.Add(b.MaxDuration-b.duration), errDurationBudget)
+	}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			b.mu.Lock()
+			b.duration += time.Since(start)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// turns errors caused by the duration budget into a BudgetExceededError
+func (b *Budget) wrap(ctx context.Context, err error) error {
+	if b == nil || err == nil || !errors.Is(context.Cause(ctx), errDurationBudget) {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &BudgetExceededError{Limit: "duration", Max: b.MaxDuration.Seconds(), Spent: b.MaxDuration.Seconds()}
+}
+
+// charges a completion against the request budget, if any
+func (sdk *SDK) chargeBudget(budget *Budget, resp *CompletionResponse, opts *Options) *BudgetExceededError {
+	if budget == nil {
+		return nil
+	}
+	cost := sdk.completionCost(resp, opts)
+	if budget.Prices != nil {
+		cost, _ = budget.Prices.Cost(sdk.responseProvider(resp), responseModel(resp, opts), resp.Usage)
+	}
+	return budget.charge(resp, cost)
+}
+
+// collects the usage events of a stream, charge bills them to the budget once the stream has ended
+// streams are not cut off when they exceed the budget, the next request fails instead
+func (sdk *SDK) chargeStream(opts *Options) (*Options, func()) {
+	if opts.Budget == nil {
+		return opts, func() {}
+	}
+
+	var mu sync.Mutex
+	var usage Usage
+	opts = withStreamEvents(opts, func(event StreamEvent) {
+		if event.Type == StreamEventUsage {
+			mu.Lock()
+			usage = event.Usage
+			mu.Unlock()
+		}
+	})
+
+	var once sync.Once
+	return opts, func() {
+		once.Do(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			sdk.chargeBudget(opts.Budget, &CompletionResponse{Usage: usage}, opts)
+		})
+	}
+}
+
+// attaches the transcript to a BudgetExceededError
+func withTranscript(err error, transcript []Message) error {
+	var budgetErr *BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		budgetErr.Messages = append([]Message{}, transcript...)
+	}
+	return err
+}
+
+// stops the budget timer once the stream is closed
+type budgetStream struct {
+	io.ReadCloser
+	stop func()
+}
+
+func (s *budgetStream) Close() error {
+	err := s.ReadCloser.Close()
+	s.stop()
+	return err
+}
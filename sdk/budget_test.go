@@ -0,0 +1,104 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func TestBudgetChargesStreams(t *testing.T) {
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return streamReply(30, 20, "hel", "lo")
+	})
+	client := sdk.NewSDK(provider)
+	budget := &sdk.Budget{MaxTokens: 60}
+
+	stream := func() *sdk.Response {
+		return client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+			Messages: []sdk.Message{{Role: "user", Content: "hi"}},
+			Stream:   true,
+			Budget:   budget,
+		})
+	}
+
+	resp := stream()
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if text, _ := io.ReadAll(resp.Stream); string(text) != "hello" {
+		t.Fatalf("stream = %q", text)
+	}
+	resp.Stream.Close()
+
+	if tokens, _, _ := budget.Spent(); tokens != 50 {
+		t.Fatalf("spent %d tokens, want 50", tokens)
+	}
+
+	// the second stream crosses the limit, the third one is refused
+	resp = stream()
+	io.ReadAll(resp.Stream)
+	resp.Stream.Close()
+
+	var budgetErr *sdk.BudgetExceededError
+	if resp = stream(); !errors.As(resp.Error, &budgetErr) || budgetErr.Limit != "tokens" {
+		t.Fatalf("err = %v, want a tokens BudgetExceededError", resp.Error)
+	}
+}
+
+func TestBudgetStopsStreamingToolLoop(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if stream {
+			return streamReply(10, 10, "streamed again")
+		}
+		return textReply("final answer", 80, 40)
+	})
+
+	resp := sdk.NewSDK(provider).ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: []sdk.Message{{Role: "user", Content: "hi"}},
+		Tools:    echoTool(),
+		Stream:   true,
+		Budget:   &sdk.Budget{MaxTokens: 100},
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	text, err := io.ReadAll(resp.Stream)
+	if err != nil || string(text) != "final answer" {
+		t.Fatalf("stream = %q, %v", text, err)
+	}
+	if got := calls.calls(); got != 1 {
+		t.Fatalf("made %d provider calls, want 1 once the budget is exceeded", got)
+	}
+}
+
+func TestBudgetChargesStreamingToolLoop(t *testing.T) {
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if stream {
+			return streamReply(15, 5, "done")
+		}
+		if n == 0 {
+			return toolReply("call-1", "lookup", 10, 5)
+		}
+		return textReply("unused", 10, 5)
+	})
+	budget := &sdk.Budget{}
+
+	resp := sdk.NewSDK(provider).ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: []sdk.Message{{Role: "user", Content: "hi"}},
+		Tools:    echoTool(),
+		Stream:   true,
+		Budget:   budget,
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	io.ReadAll(resp.Stream)
+	resp.Stream.Close()
+
+	if tokens, _, _ := budget.Spent(); tokens != 35 {
+		t.Fatalf("spent %d tokens, want 35 for the tool step and the stream", tokens)
+	}
+}
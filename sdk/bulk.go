@@ -0,0 +1,126 @@
+// concurrent bulk completions
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type BulkOptions struct {
+	Concurrency       int           // requests in flight, defaults to 4
+	Retries           int           // extra attempts per provider call on transient errors
+	Backoff           time.Duration // first retry delay, doubled on every attempt, defaults to 1s
+	RequestsPerSecond float64       // provider calls per second shared by the whole batch, including tool steps and retries, 0 is unlimited
+	OnProgress        func(done, failed, total int)
+}
+
+// returned by CompleteMany when some requests failed
+// the failed responses carry their own Error
+type BulkError struct {
+	Failed []int // indexes of the failed requests
+	Errors []error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d requests failed, first: %v", len(e.Failed), e.Errors[0])
+}
+
+func (e *BulkError) Unwrap() []error {
+	return e.Errors
+}
+
+// runs the requests concurrently and returns the responses in request order
+// streaming is disabled for bulk requests; the error is a *BulkError when some requests failed
+// retries repeat the failed provider call only, tools of earlier steps are not executed again
+func (sdk *SDK) CompleteMany(ctx context.Context, reqs []CompletionRequest, opts *BulkOptions) ([]*Response, error) {
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	limiter := &tokenBucket{} // unlimited
+	if opts.RequestsPerSecond > 0 {
+		burst := max(1, opts.RequestsPerSecond)
+		limiter = &tokenBucket{capacity: burst, tokens: burst, rate: opts.RequestsPerSecond, last: time.Now()}
+	}
+
+	// retries and the rate limit wrap every provider call instead of the whole tool loop
+	limited := &ProviderFuncs{
+		Next: sdk.provider,
+		Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+			if err := limiter.wait(ctx, 1); err != nil {
+				return nil, err
+			}
+			return sdk.provider.CreateCompletion(ctx, messages, opts)
+		},
+	}
+	client := *sdk
+	client.provider = Retry(opts.Retries+1, backoff)(limited)
+
+	responses := make([]*Response, len(reqs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	var mu sync.Mutex
+	done, failed := 0, 0
+	finish := func(resp *Response) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if resp.Error != nil {
+			failed++
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, failed, len(reqs))
+		}
+	}
+
+	for i := range reqs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			responses[i] = &Response{Error: ctx.Err()}
+			finish(responses[i])
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			req := reqs[i]
+			req.Stream = false
+
+			resp := client.ChatCompletion(ctx, &req)
+			responses[i] = resp
+			finish(resp)
+		}(i)
+	}
+	wg.Wait()
+
+	var bulkErr *BulkError
+	for i, resp := range responses {
+		if resp.Error != nil {
+			if bulkErr == nil {
+				bulkErr = &BulkError{}
+			}
+			bulkErr.Failed = append(bulkErr.Failed, i)
+			bulkErr.Errors = append(bulkErr.Errors, resp.Error)
+		}
+	}
+	if bulkErr != nil {
+		return responses, bulkErr
+	}
+	return responses, nil
+}
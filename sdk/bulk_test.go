@@ -0,0 +1,131 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// provider echoing the last message, failing on "fail" with a 400
+func echoProvider() sdk.Provider {
+	return &sdk.ProviderFuncs{
+		Completion: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (*sdk.CompletionResponse, error) {
+			content := messages[len(messages)-1].Content
+			if content == "fail" {
+				return nil, &sdk.APIError{StatusCode: http.StatusBadRequest}
+			}
+			// later requests finish first
+			time.Sleep(time.Duration(10-len(content)) * time.Millisecond)
+			return &sdk.CompletionResponse{Content: content, Role: "assistant"}, nil
+		},
+	}
+}
+
+func bulkRequests(contents ...string) []sdk.CompletionRequest {
+	reqs := make([]sdk.CompletionRequest, len(contents))
+	for i, c := range contents {
+		reqs[i] = sdk.CompletionRequest{Messages: []sdk.Message{{Role: "user", Content: c}}}
+	}
+	return reqs
+}
+
+func TestCompleteManyOrderAndErrors(t *testing.T) {
+	client := sdk.NewSDK(echoProvider())
+
+	var mu sync.Mutex
+	var progress [][3]int
+	responses, err := client.CompleteMany(context.Background(), bulkRequests("a", "fail", "abc", "abcdef", "fail"), &sdk.BulkOptions{
+		Concurrency: 5,
+		OnProgress: func(done, failed, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, [3]int{done, failed, total})
+		},
+	})
+
+	for i, want := range []string{"a", "", "abc", "abcdef", ""} {
+		if responses[i].Content != want {
+			t.Errorf("response %d = %q, want %q", i, responses[i].Content, want)
+		}
+	}
+
+	var bulkErr *sdk.BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("err = %v, want a BulkError", err)
+	}
+	if !slices.Equal(bulkErr.Failed, []int{1, 4}) || len(bulkErr.Errors) != 2 {
+		t.Fatalf("failed = %v with %d errors", bulkErr.Failed, len(bulkErr.Errors))
+	}
+	var apiErr *sdk.APIError
+	if !errors.As(err, &apiErr) || responses[1].Error == nil {
+		t.Fatalf("err = %v, want the APIErrors of the failed requests", err)
+	}
+
+	if len(progress) != 5 || progress[4] != [3]int{5, 2, 5} {
+		t.Fatalf("progress = %v", progress)
+	}
+	for i, p := range progress {
+		if p[0] != i+1 {
+			t.Fatalf("progress = %v, want done to count up", progress)
+		}
+	}
+}
+
+func TestCompleteManySharedRateLimit(t *testing.T) {
+	client := sdk.NewSDK(echoProvider())
+
+	start := time.Now()
+	_, err := client.CompleteMany(context.Background(), bulkRequests("a", "b", "c", "d", "e", "f"), &sdk.BulkOptions{
+		Concurrency:       6,
+		RequestsPerSecond: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a burst of 4, then two more calls at 4 per second
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("6 requests at 4 per second took %v", elapsed)
+	}
+}
+
+func TestCompleteManyRetriesWithoutRerunningTools(t *testing.T) {
+	var calls, executions atomic.Int32
+	provider := &sdk.ProviderFuncs{
+		Completion: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (*sdk.CompletionResponse, error) {
+			switch calls.Add(1) {
+			case 1:
+				return &sdk.CompletionResponse{Role: "assistant", ToolCalls: []sdk.ToolCallRequest{{ID: "call-1", Name: "book", Arguments: json.RawMessage(`{}`)}}}, nil
+			case 2:
+				return nil, &sdk.APIError{StatusCode: http.StatusServiceUnavailable}
+			}
+			return &sdk.CompletionResponse{Role: "assistant", Content: "booked"}, nil
+		},
+	}
+	tools := map[string]sdk.Tool{
+		"book": {Execute: func(ctx context.Context, args json.RawMessage) (any, error) {
+			executions.Add(1)
+			return "ok", nil
+		}},
+	}
+
+	reqs := bulkRequests("book a table")
+	reqs[0].Tools = tools
+	responses, err := sdk.NewSDK(provider).CompleteMany(context.Background(), reqs, &sdk.BulkOptions{Retries: 1, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if responses[0].Content != "booked" || calls.Load() != 3 {
+		t.Fatalf("response %q after %d calls", responses[0].Content, calls.Load())
+	}
+	if executions.Load() != 1 {
+		t.Fatalf("tool executed %d times, want once", executions.Load())
+	}
+}
@@ -0,0 +1,297 @@
+// response caching
+
+package sdk
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// storage backend for cached responses
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) // ttl <= 0 never expires
+}
+
+// serves identical requests from a cache store
+// streams are replayed from the cached text, streamed responses are cached once fully read
+// streamed and non-streamed responses are cached under different keys
+// replays don't call Options.OnStreamEvent, so reasoning, citations and usage events are only reported on a miss
+// hits report no usage, so they add nothing to Response.Cost or a Budget
+type CacheProvider struct {
+	Provider  Provider
+	Store     CacheStore
+	TTL       time.Duration
+	Namespace string // mixed into the keys, e.g. to separate providers sharing a store
+}
+
+func NewCacheProvider(provider Provider, store CacheStore, ttl time.Duration) *CacheProvider {
+	return &CacheProvider{
+		Provider: provider,
+		Store:    store,
+		TTL:      ttl,
+	}
+}
+
+type cacheBypassKey struct{}
+
+// skips the cache lookup for requests made with the returned context, the fresh response is still stored
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+func (c *CacheProvider) Unwrap() Provider {
+	return c.Provider
+}
+
+func (c *CacheProvider) CreateCompletion(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+	key := c.Namespace + CacheKey(messages, opts, false)
+
+	if !cacheBypassed(ctx) {
+		if data, ok := c.Store.Get(key); ok {
+			var resp CompletionResponse
+			if err := json.Unmarshal(data, &resp); err == nil {
+				resp.Usage = Usage{}
+				resp.FromCache = true
+				return &resp, nil
+			}
+		}
+	}
+
+	resp, err := c.Provider.CreateCompletion(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(resp); err == nil {
+		c.Store.Set(key, data, c.TTL)
+	}
+	return resp, nil
+}
+
+func (c *CacheProvider) CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+	key := c.Namespace + CacheKey(messages, opts, true)
+
+	if !cacheBypassed(ctx) {
+		if data, ok := c.Store.Get(key); ok {
+			var resp CompletionResponse
+			if err := json.Unmarshal(data, &resp); err == nil {
+				return io.NopCloser(strings.NewReader(resp.Content)), nil
+			}
+		}
+	}
+
+	stream, err := c.Provider.CreateCompletionStream(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStream{ReadCloser: stream, onEOF: func(text []byte) {
+		data, err := json.Marshal(&CompletionResponse{Content: string(text), Role: "assistant"})
+		if err == nil {
+			c.Store.Set(key, data, c.TTL)
+		}
+	}}, nil
+}
+
+// copies everything read and hands it to onEOF once the stream ends cleanly
+type recordingStream struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	onEOF func([]byte)
+	done  bool
+}
+
+func (s *recordingStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.buf.Write(p[:n])
+	if err == io.EOF && !s.done {
+		s.done = true
+		s.onEOF(s.buf.Bytes())
+	}
+	return n, err
+}
+
+type cacheTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema InputSchema `json:"input_schema"`
+}
+
+// canonical hash of the messages, options and tools of a request
+// stream separates streamed text, which can carry <think> markup, from full responses
+func CacheKey(messages []Message, opts *Options, stream bool) string {
+	canonical := struct {
+		Stream           bool           `json:"stream,omitempty"`
+		Messages         []Message      `json:"messages"`
+		Model            string         `json:"model,omitempty"`
+		SystemPrompt     string         `json:"system_prompt,omitempty"`
+		MaxTokens        int            `json:"max_tokens,omitempty"`
+		ReasoningEffort  string         `json:"reasoning_effort,omitempty"`
+		ThinkingBudget   int            `json:"thinking_budget,omitempty"`
+		IncludeReasoning bool           `json:"include_reasoning,omitempty"`
+		Temperature      float32        `json:"temperature,omitempty"`
+		Search           *SearchOptions `json:"search,omitempty"`
+		Tools            []cacheTool    `json:"tools,omitempty"`
+	}{Stream: stream, Messages: messages}
+
+	if opts != nil {
+		canonical.Model = opts.Model
+		canonical.SystemPrompt = opts.SystemPrompt
+		canonical.MaxTokens = opts.MaxCompletionTokens
+		canonical.ReasoningEffort = opts.ReasoningEffort
+		canonical.ThinkingBudget = opts.ThinkingBudget
+		canonical.IncludeReasoning = opts.IncludeReasoning
+		canonical.Temperature = opts.Temperature
+		canonical.Search = opts.Search
+		for name, tool := range opts.Tools {
+			canonical.Tools = append(canonical.Tools, cacheTool{name, tool.Description, tool.InputSchema})
+		}
+		sort.Slice(canonical.Tools, func(i, j int) bool { return canonical.Tools[i].Name < canonical.Tools[j].Name })
+	}
+
+	data, _ := json.Marshal(canonical)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// in-memory LRU cache
+type MemoryCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (m *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := m.entries[key]; ok {
+		el.Value = &memoryEntry{key, value, expires}
+		m.order.MoveToFront(el)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key, value, expires})
+	for m.capacity > 0 && m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// on-disk cache storing one file per key in a directory
+type DirCache struct {
+	dir string
+}
+
+type dirEntry struct {
+	Expires time.Time `json:"expires,omitzero"`
+	Value   []byte    `json:"value"`
+}
+
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+func (d *DirCache) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+func (d *DirCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry dirEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		os.Remove(d.path(key))
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+func (d *DirCache) Set(key string, value []byte, ttl time.Duration) {
+	entry := dirEntry{Value: value}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	// write then rename so readers never see partial files
+	tmp, err := os.CreateTemp(d.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
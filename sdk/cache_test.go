@@ -0,0 +1,177 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func TestCacheKeepsStreamsApart(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if stream {
+			data, _ := json.Marshal(map[string]interface{}{
+				"choices": []interface{}{map[string]interface{}{"delta": map[string]string{"reasoning": "hmm"}}},
+			})
+			return fmt.Sprintf("data: %s\n\n", data) + streamReply(5, 5, "answer")
+		}
+		return textReply("answer", 5, 5)
+	})
+	client := sdk.NewSDK(sdk.NewCacheProvider(provider, sdk.NewMemoryCache(10), 0))
+	req := func(stream bool) *sdk.CompletionRequest {
+		return &sdk.CompletionRequest{
+			Messages:         []sdk.Message{{Role: "user", Content: "hi"}},
+			Stream:           stream,
+			IncludeReasoning: true,
+		}
+	}
+
+	resp := client.ChatCompletion(context.Background(), req(true))
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	streamed, _ := io.ReadAll(resp.Stream)
+	resp.Stream.Close()
+	if string(streamed) != "<think>\nhmm\n</think>\n\nanswer" {
+		t.Fatalf("stream = %q", streamed)
+	}
+
+	// the streamed text, <think> markup included, must not become a full response
+	resp = client.ChatCompletion(context.Background(), req(false))
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if resp.Content != "answer" {
+		t.Fatalf("content = %q", resp.Content)
+	}
+	if got := calls.calls(); got != 2 {
+		t.Fatalf("made %d provider calls, want 2", got)
+	}
+
+	// both are now served from the cache
+	resp = client.ChatCompletion(context.Background(), req(true))
+	replayed, _ := io.ReadAll(resp.Stream)
+	if string(replayed) != string(streamed) {
+		t.Fatalf("replay = %q", replayed)
+	}
+	if resp = client.ChatCompletion(context.Background(), req(false)); resp.Content != "answer" {
+		t.Fatalf("cached content = %q", resp.Content)
+	}
+	if got := calls.calls(); got != 2 {
+		t.Fatalf("made %d provider calls, want 2", got)
+	}
+}
+
+func TestCacheKeyIncludesReasoning(t *testing.T) {
+	messages := []sdk.Message{{Role: "user", Content: "hi"}}
+	plain := sdk.CacheKey(messages, &sdk.Options{}, false)
+	if plain == sdk.CacheKey(messages, &sdk.Options{IncludeReasoning: true}, false) {
+		t.Fatal("IncludeReasoning does not change the key")
+	}
+	if plain == sdk.CacheKey(messages, &sdk.Options{}, true) {
+		t.Fatal("streaming does not change the key")
+	}
+}
+
+func TestCacheHitsAreNotCharged(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("answer", 30, 20)
+	})
+	client := sdk.NewSDK(sdk.NewCacheProvider(provider, sdk.NewMemoryCache(10), 0))
+	budget := &sdk.Budget{MaxTokens: 1000}
+	req := func() *sdk.CompletionRequest {
+		return &sdk.CompletionRequest{Messages: []sdk.Message{{Role: "user", Content: "hi"}}, Budget: budget}
+	}
+
+	if resp := client.ChatCompletion(context.Background(), req()); resp.Usage.TotalTokens != 50 {
+		t.Fatalf("usage = %+v", resp.Usage)
+	}
+	resp := client.ChatCompletion(context.Background(), req())
+	if resp.Error != nil || resp.Content != "answer" || calls.calls() != 1 {
+		t.Fatalf("response = %+v after %d calls, want a cache hit", resp, calls.calls())
+	}
+	if resp.Usage != (sdk.Usage{}) || resp.Cost != 0 {
+		t.Fatalf("cache hit reported usage %+v and cost %v", resp.Usage, resp.Cost)
+	}
+	if tokens, _, _ := budget.Spent(); tokens != 50 {
+		t.Fatalf("spent %d tokens, want 50 from the single provider call", tokens)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply(fmt.Sprintf("answer %d", n), 1, 1)
+	})
+	cache := sdk.NewCacheProvider(provider, sdk.NewMemoryCache(10), 0)
+	messages := []sdk.Message{{Role: "user", Content: "hi"}}
+
+	cache.CreateCompletion(context.Background(), messages, nil)
+	resp, _ := cache.CreateCompletion(sdk.WithCacheBypass(context.Background()), messages, nil)
+	if resp.Content != "answer 1" || resp.FromCache {
+		t.Fatalf("bypassed response = %+v", resp)
+	}
+
+	// the fresh response replaced the cached one
+	resp, _ = cache.CreateCompletion(context.Background(), messages, nil)
+	if resp.Content != "answer 1" || !resp.FromCache || calls.calls() != 2 {
+		t.Fatalf("response = %+v after %d calls", resp, calls.calls())
+	}
+}
+
+func TestMemoryCacheExpiryAndEviction(t *testing.T) {
+	m := sdk.NewMemoryCache(2)
+	m.Set("short", []byte("1"), 10*time.Millisecond)
+	m.Set("forever", []byte("2"), 0)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := m.Get("short"); ok {
+		t.Fatal("expired entry still served")
+	}
+	if v, ok := m.Get("forever"); !ok || string(v) != "2" {
+		t.Fatalf("forever = %q, %v", v, ok)
+	}
+
+	// "b" is the least recently used once "a" is read
+	m.Set("a", []byte("a"), 0)
+	m.Set("b", []byte("b"), 0)
+	m.Get("a")
+	m.Set("c", []byte("c"), 0)
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "forever": false} {
+		if _, ok := m.Get(key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestDirCache(t *testing.T) {
+	dir := t.TempDir()
+	d, err := sdk.NewDirCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("key", []byte("value"), 0)
+	d.Set("short", []byte("value"), 10*time.Millisecond)
+
+	// entries survive a new store on the same directory
+	d, _ = sdk.NewDirCache(dir)
+	if v, ok := d.Get("key"); !ok || string(v) != "value" {
+		t.Fatalf("key = %q, %v", v, ok)
+	}
+	if _, ok := d.Get("missing"); ok {
+		t.Fatal("missing key found")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := d.Get("short"); ok {
+		t.Fatal("expired entry still served")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("%d files left, want the expired entry removed", len(entries))
+	}
+}
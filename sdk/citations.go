@@ -0,0 +1,30 @@
+// citations and search options of providers with built-in web search
+
+package sdk
+
+// a source the answer is based on
+type Citation struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+	Date    string `json:"date,omitempty"` // publication date, when reported
+
+	// byte offsets of the cited span in Content, both zero when the provider does not report one
+	Start int `json:"start,omitempty"`
+	End   int `json:"end,omitempty"`
+}
+
+// an image found by the search
+type SearchImage struct {
+	URL       string `json:"url"`
+	OriginURL string `json:"origin_url,omitempty"` // page the image was found on
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+// web search settings for providers with built-in search (Perplexity)
+type SearchOptions struct {
+	DomainFilter  []string `json:"domain_filter,omitempty"`  // e.g. "wikipedia.org", a leading "-" excludes the domain
+	RecencyFilter string   `json:"recency_filter,omitempty"` // "hour", "day", "week", "month" or "year"
+	ReturnImages  bool     `json:"return_images,omitempty"`
+}
@@ -0,0 +1,169 @@
+// conversation sessions with managed history
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// holds a running conversation bound to an SDK
+// every Send appends the user turn and the assistant/tool transcript to the history
+type Conversation struct {
+	sdk      *SDK
+	defaults CompletionRequest
+
+	sending  sync.Mutex // serializes Send, held during the request
+	mu       sync.Mutex // guards the history, never held during a request
+	messages []Message
+	turns    []int // index into messages where each turn starts
+}
+
+// creates a conversation using defaults for model, system prompt, tools and other options
+// defaults.Messages, if set, seeds the history
+func (sdk *SDK) NewConversation(defaults *CompletionRequest) *Conversation {
+	c := &Conversation{sdk: sdk}
+	if defaults != nil {
+		c.defaults = *defaults
+		c.messages = append([]Message{}, defaults.Messages...)
+		c.defaults.Messages = nil
+	}
+	c.defaults.Stream = false
+	return c
+}
+
+// restores a conversation saved with MarshalJSON and binds it to the SDK
+// defaults supply what is not serialized, such as tools, the saved state overrides the rest
+func (sdk *SDK) LoadConversation(data []byte, defaults *CompletionRequest) (*Conversation, error) {
+	c := sdk.NewConversation(defaults)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// sends a user message and records the reply in the history
+// the history is left untouched when the request fails
+// sends are serialized, Messages and MarshalJSON don't wait for a pending request
+func (c *Conversation) Send(ctx context.Context, userText string) *Response {
+	if c.sdk == nil {
+		return &Response{Error: errors.New("conversation is not bound to an SDK, create it with NewConversation or LoadConversation")}
+	}
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+
+	userMsg := Message{Role: "user", Content: userText}
+
+	c.mu.Lock()
+	req := c.defaults
+	req.Messages = append(append([]Message{}, c.messages...), userMsg)
+	c.mu.Unlock()
+
+	resp := c.sdk.ChatCompletion(ctx, &req)
+	if resp.Error != nil {
+		return resp
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.turns = append(c.turns, len(c.messages))
+	c.messages = append(c.messages, userMsg)
+	c.messages = append(c.messages, resp.Messages...)
+
+	return resp
+}
+
+// returns a copy of the conversation history
+func (c *Conversation) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message{}, c.messages...)
+}
+
+// removes the last turn, returns false when there is nothing to undo
+func (c *Conversation) Undo() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.turns) == 0 {
+		return false
+	}
+	last := c.turns[len(c.turns)-1]
+	c.turns = c.turns[:len(c.turns)-1]
+	c.messages = c.messages[:last]
+	return true
+}
+
+// returns an independent copy of the conversation sharing the same SDK and defaults
+func (c *Conversation) Fork() *Conversation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &Conversation{
+		sdk:      c.sdk,
+		defaults: c.defaults,
+		messages: append([]Message{}, c.messages...),
+		turns:    append([]int{}, c.turns...),
+	}
+}
+
+type conversationState struct {
+	Model           string        `json:"model,omitempty"`
+	SystemPrompt    string        `json:"system_prompt,omitempty"`
+	SystemCache     *CacheControl `json:"system_cache,omitempty"`
+	MaxTokens       int           `json:"max_tokens,omitempty"`
+	Temperature     float32       `json:"temperature,omitempty"`
+	ReasoningEffort string        `json:"reasoning_effort,omitempty"`
+	Messages        []Message     `json:"messages"`
+	Turns           []int         `json:"turns,omitempty"`
+}
+
+// serializes the history and defaults, tools are not serialized
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return json.Marshal(conversationState{
+		Model:           c.defaults.Model,
+		SystemPrompt:    c.defaults.SystemPrompt,
+		SystemCache:     c.defaults.SystemCache,
+		MaxTokens:       c.defaults.MaxTokens,
+		Temperature:     c.defaults.Temperature,
+		ReasoningEffort: c.defaults.ReasoningEffort,
+		Messages:        c.messages,
+		Turns:           c.turns,
+	})
+}
+
+// restores a conversation saved with MarshalJSON
+// unmarshal into a conversation created by NewConversation to keep the SDK binding and tools, or use LoadConversation
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var state conversationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	for _, t := range state.Turns {
+		if t < 0 || t > len(state.Messages) {
+			return fmt.Errorf("invalid conversation turn index %d", t)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.defaults.Model = state.Model
+	c.defaults.SystemPrompt = state.SystemPrompt
+	c.defaults.SystemCache = state.SystemCache
+	c.defaults.MaxTokens = state.MaxTokens
+	c.defaults.Temperature = state.Temperature
+	c.defaults.ReasoningEffort = state.ReasoningEffort
+	c.messages = state.Messages
+	c.turns = state.Turns
+	return nil
+}
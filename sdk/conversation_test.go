@@ -0,0 +1,159 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func roles(messages []sdk.Message) []string {
+	var r []string
+	for _, m := range messages {
+		r = append(r, m.Role)
+	}
+	return r
+}
+
+func sameRoles(messages []sdk.Message, want ...string) bool {
+	got := roles(messages)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestConversationSendRecordsToolTranscript(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if n == 0 {
+			return toolReply("call-1", "lookup", 10, 5)
+		}
+		return textReply("answer", 10, 5)
+	})
+	conv := sdk.NewSDK(provider).NewConversation(&sdk.CompletionRequest{Tools: echoTool()})
+
+	if resp := conv.Send(context.Background(), "question"); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	history := conv.Messages()
+	if !sameRoles(history, "user", "assistant", "tool", "assistant") {
+		t.Fatalf("history roles = %v", roles(history))
+	}
+	if history[2].Content != `"found"` || history[3].Content != "answer" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	// the next turn sends the whole transcript
+	conv.Send(context.Background(), "again")
+	if got := len(calls.requests[calls.calls()-1]); got != 5 {
+		t.Fatalf("sent %d messages, want the 4 recorded ones and the new user message", got)
+	}
+}
+
+func TestConversationForkAndUndo(t *testing.T) {
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("reply", 1, 1)
+	})
+	conv := sdk.NewSDK(provider).NewConversation(nil)
+	conv.Send(context.Background(), "one")
+
+	branch := conv.Fork()
+	branch.Send(context.Background(), "two")
+	if len(conv.Messages()) != 2 || len(branch.Messages()) != 4 {
+		t.Fatalf("conversation has %d messages and its fork %d, want 2 and 4", len(conv.Messages()), len(branch.Messages()))
+	}
+
+	if !branch.Undo() || len(branch.Messages()) != 2 {
+		t.Fatalf("after undo the fork has %d messages, want 2", len(branch.Messages()))
+	}
+	if !branch.Undo() || len(branch.Messages()) != 0 {
+		t.Fatalf("after two undos the fork has %d messages, want 0", len(branch.Messages()))
+	}
+	if branch.Undo() {
+		t.Fatal("undo on an empty conversation reported a change")
+	}
+	if len(conv.Messages()) != 2 {
+		t.Fatal("undo on the fork changed the original")
+	}
+}
+
+func TestConversationMarshalRoundTrip(t *testing.T) {
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("reply", 1, 1)
+	})
+	client := sdk.NewSDK(provider)
+	conv := client.NewConversation(&sdk.CompletionRequest{Model: "test-model", SystemPrompt: "be brief"})
+	conv.Send(context.Background(), "one")
+	conv.Send(context.Background(), "two")
+
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := client.LoadConversation(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := json.Marshal(restored)
+	if string(again) != string(data) {
+		t.Fatalf("round trip = %s, want %s", again, data)
+	}
+
+	// turns survive, undo drops only the last one
+	if !restored.Undo() || len(restored.Messages()) != 2 {
+		t.Fatalf("after undo the restored conversation has %d messages, want 2", len(restored.Messages()))
+	}
+	if resp := restored.Send(context.Background(), "three"); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+}
+
+func TestConversationWithoutSDK(t *testing.T) {
+	var conv sdk.Conversation
+	if err := json.Unmarshal([]byte(`{"messages":[]}`), &conv); err != nil {
+		t.Fatal(err)
+	}
+	if resp := conv.Send(context.Background(), "hi"); resp.Error == nil {
+		t.Fatal("Send on an unbound conversation succeeded")
+	}
+}
+
+func TestConversationReadableDuringSend(t *testing.T) {
+	release := make(chan struct{})
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		<-release
+		return textReply("reply", 1, 1)
+	})
+	conv := sdk.NewSDK(provider).NewConversation(nil)
+
+	done := make(chan struct{})
+	go func() {
+		conv.Send(context.Background(), "hi")
+		close(done)
+	}()
+
+	read := make(chan struct{})
+	go func() {
+		conv.Messages()
+		json.Marshal(conv)
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		t.Fatal("Messages blocked while a Send was pending")
+	}
+
+	close(release)
+	<-done
+	if len(conv.Messages()) != 2 {
+		t.Fatalf("history has %d messages, want 2", len(conv.Messages()))
+	}
+}
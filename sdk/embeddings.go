@@ -0,0 +1,69 @@
+// text embeddings
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// what the embedded text is used for, providers that distinguish them embed queries and documents differently
+type InputType string
+
+const (
+	InputTypeQuery    InputType = "query"
+	InputTypeDocument InputType = "document"
+)
+
+type EmbedRequest struct {
+	Model      string    // defaults to the provider's embedding model when supported
+	Input      []string  // texts to embed, split into batches automatically
+	Dimensions int       // output dimensions, for models supporting shortened embeddings
+	InputType  InputType // optional
+}
+
+type EmbedResponse struct {
+	Embeddings [][]float32 // one per input, in order
+	Model      string
+	Usage      Usage
+}
+
+// implemented by providers with an embeddings endpoint
+type Embedder interface {
+	Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error)
+	MaxEmbedInputs() int // inputs per request accepted by the provider
+}
+
+// embeds the inputs, splitting them into batches the provider accepts
+// returns ErrUnsupported when the provider has no embeddings endpoint
+func (sdk *SDK) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	embedder, ok := capability[Embedder](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	batchSize := embedder.MaxEmbedInputs()
+	if batchSize <= 0 {
+		batchSize = len(req.Input)
+	}
+
+	result := &EmbedResponse{Embeddings: make([][]float32, 0, len(req.Input))}
+	for start := 0; start < len(req.Input); start += batchSize {
+		end := min(start+batchSize, len(req.Input))
+
+		batch := *req
+		batch.Input = req.Input[start:end]
+		resp, err := embedder.Embed(ctx, &batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings) != len(batch.Input) {
+			return nil, fmt.Errorf("embedding batch %d-%d: got %d embeddings for %d inputs", start, end, len(resp.Embeddings), len(batch.Input))
+		}
+
+		result.Embeddings = append(result.Embeddings, resp.Embeddings...)
+		result.Model = resp.Model
+		result.Usage = result.Usage.Add(resp.Usage)
+	}
+	return result, nil
+}
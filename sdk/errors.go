@@ -2,14 +2,121 @@
 
 package sdk
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// returned when the provider does not implement an optional capability
+var ErrUnsupported = errors.New("not supported by this provider")
+
+// returned when a request uses a parameter the model does not support, see ModelCatalog
+var ErrUnsupportedParameter = errors.New("parameter not supported by this model")
+
+// wrapped by provider errors for responses blocked by safety filters
+var ErrContentFiltered = errors.New("content filtered")
+
+// finish reasons of responses cut short by safety filters
+// content_filter for OpenAI compatible APIs, refusal for Anthropic
+var filteredFinishReasons = map[string]bool{
+	"content_filter": true,
+	"refusal":        true,
+}
+
+// returns an error wrapping ErrContentFiltered when resp was stopped by a safety filter
+func filteredResponse(resp *CompletionResponse) error {
+	if resp == nil || !filteredFinishReasons[resp.FinishReason] {
+		return nil
+	}
+	return fmt.Errorf("%w: finish reason %s", ErrContentFiltered, resp.FinishReason)
+}
 
 type APIError struct {
 	StatusCode int
 	Message    string
 	Body       []byte
+	Header     http.Header
+}
+
+// delay requested by the Retry-After header, zero when absent
+func (e *APIError) RetryAfter() time.Duration {
+	if e.Header == nil {
+		return 0
+	}
+	v := e.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 func (e *APIError) Error() string {
 	return fmt.Sprintf("APIError: %d - %s", e.StatusCode, e.Message)
 }
+
+// coarse error categories used to decide on failover and retries
+type ErrorClass uint
+
+const (
+	ErrorServer        ErrorClass = 1 << iota // 5xx responses
+	ErrorRateLimit                            // 429 responses
+	ErrorTimeout                              // deadlines, 408 and network timeouts
+	ErrorNetwork                              // connection failures
+	ErrorAuth                                 // 401 and 403 responses
+	ErrorContentFilter                        // responses blocked by safety filters
+	ErrorClient                               // other 4xx responses
+)
+
+// classes worth retrying on another attempt or backend
+const ErrorTransient = ErrorServer | ErrorRateLimit | ErrorTimeout | ErrorNetwork
+
+// categorizes err, returns 0 for unknown errors and nil
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return 0
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.StatusCode; {
+		case code == 429:
+			return ErrorRateLimit
+		case code == 408:
+			return ErrorTimeout
+		case code == 401 || code == 403:
+			return ErrorAuth
+		case code >= 500:
+			return ErrorServer
+		case code >= 400:
+			return ErrorClient
+		}
+		return 0
+	}
+
+	if errors.Is(err, ErrContentFiltered) {
+		return ErrorContentFilter
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	return 0
+}
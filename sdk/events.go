@@ -0,0 +1,37 @@
+// typed stream events besides the streamed text
+
+package sdk
+
+type StreamEventType string
+
+const (
+	StreamEventText      StreamEventType = "text"
+	StreamEventReasoning StreamEventType = "reasoning" // reasoning or thinking deltas
+	StreamEventCitations StreamEventType = "citations" // all citations and images found so far
+	StreamEventUsage     StreamEventType = "usage"     // token usage so far, the last one is final
+)
+
+// event of a streamed completion, text events are also written to the stream
+type StreamEvent struct {
+	Type      StreamEventType
+	Text      string
+	Citations []Citation    // citations events only
+	Images    []SearchImage // citations events only
+	Usage     Usage         // usage events only
+}
+
+// returns a copy of opts whose OnStreamEvent also calls fn, used to observe streams
+func withStreamEvents(opts *Options, fn func(StreamEvent)) *Options {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	next := o.OnStreamEvent
+	o.OnStreamEvent = func(event StreamEvent) {
+		fn(event)
+		if next != nil {
+			next(event)
+		}
+	}
+	return &o
+}
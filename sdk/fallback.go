@@ -0,0 +1,171 @@
+// fallback provider chain
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+type FallbackBackend struct {
+	Name     string // reported as CompletionResponse.Provider
+	Provider Provider
+	Model    string // overrides Options.Model when set
+}
+
+// tries an ordered list of backends, moving to the next one on failover errors
+// with ErrorContentFilter in FailoverOn, completions finished by a safety filter (content_filter, refusal) fail over too
+type FallbackProvider struct {
+	Backends   []FallbackBackend
+	FailoverOn ErrorClass // defaults to ErrorTransient
+
+	// called with the backend that served a request, also for streams
+	OnServed func(backend FallbackBackend)
+	// called when a backend fails and the next one is tried
+	OnFailover func(backend FallbackBackend, err error)
+}
+
+func NewFallbackProvider(backends ...FallbackBackend) *FallbackProvider {
+	return &FallbackProvider{
+		Backends:   backends,
+		FailoverOn: ErrorTransient,
+	}
+}
+
+// returns the first backend, capability calls such as Embed don't fail over
+func (f *FallbackProvider) Unwrap() Provider {
+	if len(f.Backends) == 0 {
+		return nil
+	}
+	return f.Backends[0].Provider
+}
+
+func (f *FallbackProvider) CreateCompletion(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+	var lastErr error
+	for _, b := range f.Backends {
+		resp, err := b.Provider.CreateCompletion(ctx, messages, b.options(opts))
+		if err == nil && f.failoverOn()&ErrorContentFilter != 0 {
+			err = filteredResponse(resp)
+		}
+		if err == nil {
+			if resp.Provider == "" {
+				resp.Provider = b.Name
+			}
+			if resp.Model == "" {
+				resp.Model = b.options(opts).Model
+			}
+			f.served(b)
+			return resp, nil
+		}
+
+		lastErr = err
+		if !f.shouldFailover(ctx, err) {
+			return nil, err
+		}
+		f.failover(b, err)
+	}
+	return nil, f.exhausted(lastErr)
+}
+
+// fails over only while the stream has not produced any bytes
+// streams carry no finish reason, so filtered streams don't fail over
+func (f *FallbackProvider) CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+	var lastErr error
+	for _, b := range f.Backends {
+		stream, err := b.Provider.CreateCompletionStream(ctx, messages, b.options(opts))
+		if err == nil {
+			var head []byte
+			head, err = readFirstChunk(stream)
+			if err == nil || len(head) > 0 || err == io.EOF {
+				f.served(b)
+				rest := io.Reader(stream)
+				if err != nil {
+					rest = errReader{err}
+				}
+				return &peekedStream{
+					Reader: io.MultiReader(bytes.NewReader(head), rest),
+					closer: stream,
+				}, nil
+			}
+			stream.Close()
+		}
+
+		lastErr = err
+		if !f.shouldFailover(ctx, err) {
+			return nil, err
+		}
+		f.failover(b, err)
+	}
+	return nil, f.exhausted(lastErr)
+}
+
+func (b FallbackBackend) options(opts *Options) *Options {
+	if b.Model == "" {
+		return opts
+	}
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	o.Model = b.Model
+	return &o
+}
+
+func (f *FallbackProvider) shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return ClassifyError(err)&f.failoverOn() != 0
+}
+
+func (f *FallbackProvider) failoverOn() ErrorClass {
+	if f.FailoverOn == 0 {
+		return ErrorTransient
+	}
+	return f.FailoverOn
+}
+
+func (f *FallbackProvider) served(b FallbackBackend) {
+	if f.OnServed != nil {
+		f.OnServed(b)
+	}
+}
+
+func (f *FallbackProvider) failover(b FallbackBackend, err error) {
+	if f.OnFailover != nil {
+		f.OnFailover(b, err)
+	}
+}
+
+func (f *FallbackProvider) exhausted(lastErr error) error {
+	if lastErr == nil {
+		return errors.New("fallback: no backends configured")
+	}
+	return lastErr
+}
+
+// reads until the stream yields data or fails
+func readFirstChunk(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 || err != nil {
+			return buf[:n], err
+		}
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+type peekedStream struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (s *peekedStream) Close() error {
+	return s.closer.Close()
+}
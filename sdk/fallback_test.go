@@ -0,0 +1,147 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// backend answering completions with resp or err, and recording the calls
+func fakeBackend(name string, calls *[]string, resp *sdk.CompletionResponse, err error) sdk.FallbackBackend {
+	return sdk.FallbackBackend{Name: name, Provider: &sdk.ProviderFuncs{
+		Completion: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (*sdk.CompletionResponse, error) {
+			*calls = append(*calls, name+":"+opts.Model)
+			if err != nil {
+				return nil, err
+			}
+			r := *resp
+			return &r, nil
+		},
+	}}
+}
+
+// backend streaming chunks then failing with err, a nil err ends the stream cleanly
+func streamBackend(name string, err error, chunks ...string) sdk.FallbackBackend {
+	return sdk.FallbackBackend{Name: name, Provider: &sdk.ProviderFuncs{
+		Stream: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (io.ReadCloser, error) {
+			var readers []io.Reader
+			for _, c := range chunks {
+				readers = append(readers, strings.NewReader(c))
+			}
+			if err != nil {
+				readers = append(readers, failingReader{err})
+			}
+			return io.NopCloser(io.MultiReader(readers...)), nil
+		},
+	}}
+}
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestFallbackOrder(t *testing.T) {
+	var calls, served, failed []string
+	primary := fakeBackend("primary", &calls, nil, &sdk.APIError{StatusCode: http.StatusServiceUnavailable})
+	primary.Model = "model-a"
+	secondary := fakeBackend("secondary", &calls, &sdk.CompletionResponse{Content: "ok"}, nil)
+	secondary.Model = "model-b"
+	unused := fakeBackend("unused", &calls, &sdk.CompletionResponse{Content: "late"}, nil)
+
+	f := sdk.NewFallbackProvider(primary, secondary, unused)
+	f.OnServed = func(b sdk.FallbackBackend) { served = append(served, b.Name) }
+	f.OnFailover = func(b sdk.FallbackBackend, err error) { failed = append(failed, b.Name) }
+
+	resp, err := f.CreateCompletion(context.Background(), nil, &sdk.Options{Model: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "ok" || resp.Provider != "secondary" || resp.Model != "model-b" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if strings.Join(calls, ",") != "primary:model-a,secondary:model-b" {
+		t.Fatalf("calls = %v", calls)
+	}
+	if strings.Join(served, ",") != "secondary" || strings.Join(failed, ",") != "primary" {
+		t.Fatalf("served %v, failed over %v", served, failed)
+	}
+}
+
+func TestFallbackStopsOnClientErrors(t *testing.T) {
+	var calls []string
+	f := sdk.NewFallbackProvider(
+		fakeBackend("primary", &calls, nil, &sdk.APIError{StatusCode: http.StatusBadRequest}),
+		fakeBackend("secondary", &calls, &sdk.CompletionResponse{Content: "ok"}, nil),
+	)
+
+	_, err := f.CreateCompletion(context.Background(), nil, &sdk.Options{})
+	var apiErr *sdk.APIError
+	if !errors.As(err, &apiErr) || len(calls) != 1 {
+		t.Fatalf("err = %v after %d calls, want the 400 from the first backend", err, len(calls))
+	}
+}
+
+func TestFallbackOnContentFilter(t *testing.T) {
+	for _, reason := range []string{"content_filter", "refusal"} {
+		var calls []string
+		backends := []sdk.FallbackBackend{
+			fakeBackend("primary", &calls, &sdk.CompletionResponse{FinishReason: reason}, nil),
+			fakeBackend("secondary", &calls, &sdk.CompletionResponse{Content: "ok", FinishReason: "stop"}, nil),
+		}
+
+		// only when asked for
+		resp, err := sdk.NewFallbackProvider(backends...).CreateCompletion(context.Background(), nil, &sdk.Options{})
+		if err != nil || resp.Provider != "primary" {
+			t.Fatalf("%s without ErrorContentFilter: served by %q, err %v", reason, resp.Provider, err)
+		}
+
+		f := sdk.NewFallbackProvider(backends...)
+		f.FailoverOn = sdk.ErrorTransient | sdk.ErrorContentFilter
+		resp, err = f.CreateCompletion(context.Background(), nil, &sdk.Options{})
+		if err != nil || resp.Provider != "secondary" {
+			t.Fatalf("%s: served by %q, err %v", reason, resp.Provider, err)
+		}
+
+		// the last filtered response is reported as an error
+		f.Backends = backends[:1]
+		if _, err := f.CreateCompletion(context.Background(), nil, &sdk.Options{}); !errors.Is(err, sdk.ErrContentFiltered) {
+			t.Fatalf("%s: err = %v, want ErrContentFiltered", reason, err)
+		}
+	}
+}
+
+func TestFallbackStreamsBeforeFirstByte(t *testing.T) {
+	unavailable := &sdk.APIError{StatusCode: http.StatusServiceUnavailable}
+
+	var served []string
+	f := sdk.NewFallbackProvider(streamBackend("primary", unavailable), streamBackend("secondary", nil, "hel", "lo"))
+	f.OnServed = func(b sdk.FallbackBackend) { served = append(served, b.Name) }
+
+	stream, err := f.CreateCompletionStream(context.Background(), nil, &sdk.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := io.ReadAll(stream)
+	if err != nil || string(text) != "hello" || strings.Join(served, ",") != "secondary" {
+		t.Fatalf("stream = %q, %v, served by %v", text, err, served)
+	}
+
+	// once a byte was emitted the error reaches the reader and there is no failover
+	served = nil
+	f = sdk.NewFallbackProvider(streamBackend("primary", unavailable, "par"), streamBackend("secondary", nil, "hello"))
+	f.OnServed = func(b sdk.FallbackBackend) { served = append(served, b.Name) }
+
+	stream, err = f.CreateCompletionStream(context.Background(), nil, &sdk.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err = io.ReadAll(stream)
+	if string(text) != "par" || !errors.Is(err, unavailable) || strings.Join(served, ",") != "primary" {
+		t.Fatalf("stream = %q, %v, served by %v", text, err, served)
+	}
+}
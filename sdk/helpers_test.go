@@ -0,0 +1,104 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/xerohard/ai/v2/base"
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// APICaller replaying OpenAI style bodies and recording the requests
+type scriptedCaller struct {
+	mu       sync.Mutex
+	requests [][]sdk.Message
+	respond  func(n int, messages []sdk.Message, stream bool) string
+}
+
+func (c *scriptedCaller) CallAPI(ctx context.Context, messages []sdk.Message, stream bool, opts *sdk.Options) (io.ReadCloser, error) {
+	c.mu.Lock()
+	n := len(c.requests)
+	c.requests = append(c.requests, append([]sdk.Message{}, messages...))
+	c.mu.Unlock()
+	return io.NopCloser(strings.NewReader(c.respond(n, messages, stream))), nil
+}
+
+func (c *scriptedCaller) ParseResponse(body io.Reader, onChunk func(string) error) error {
+	return base.ParseJsonStream(body, onChunk)
+}
+
+func (c *scriptedCaller) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
+
+func (c *scriptedCaller) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.requests)
+}
+
+func newScripted(respond func(n int, messages []sdk.Message, stream bool) string) (*base.Provider, *scriptedCaller) {
+	c := &scriptedCaller{respond: respond}
+	return &base.Provider{APICaller: c}, c
+}
+
+// non-streaming reply with the given content and usage
+func textReply(content string, input, output int) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"model": "test-model",
+		"choices": []interface{}{map[string]interface{}{
+			"finish_reason": "stop",
+			"message":       map[string]interface{}{"role": "assistant", "content": content},
+		}},
+		"usage": map[string]int{"prompt_tokens": input, "completion_tokens": output, "total_tokens": input + output},
+	})
+	return string(data)
+}
+
+// non-streaming reply calling a tool
+func toolReply(id, name string, input, output int) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"model": "test-model",
+		"choices": []interface{}{map[string]interface{}{
+			"finish_reason": "tool_calls",
+			"message": map[string]interface{}{
+				"role":       "assistant",
+				"tool_calls": []interface{}{map[string]string{"id": id, "name": name, "arguments": "{}"}},
+			},
+		}},
+		"usage": map[string]int{"prompt_tokens": input, "completion_tokens": output, "total_tokens": input + output},
+	})
+	return string(data)
+}
+
+// streamed reply carrying the chunks and a final usage chunk
+func streamReply(input, output int, chunks ...string) string {
+	var b strings.Builder
+	for _, c := range chunks {
+		data, _ := json.Marshal(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"delta": map[string]string{"content": c}}},
+		})
+		fmt.Fprintf(&b, "data: %s\n\n", data)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{},
+		"usage":   map[string]int{"prompt_tokens": input, "completion_tokens": output, "total_tokens": input + output},
+	})
+	fmt.Fprintf(&b, "data: %s\n\ndata: [DONE]\n\n", data)
+	return b.String()
+}
+
+func echoTool() map[string]sdk.Tool {
+	return map[string]sdk.Tool{
+		"lookup": {
+			Description: "looks something up",
+			Execute: func(ctx context.Context, args json.RawMessage) (any, error) {
+				return "found", nil
+			},
+		},
+	}
+}
@@ -0,0 +1,246 @@
+// history strategies for keeping conversations inside the context window
+
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// rewrites the history before it is sent to the provider
+// strategies never separate an assistant message with tool calls from its tool results
+type HistoryStrategy interface {
+	Apply(ctx context.Context, messages []Message, opts *Options) ([]Message, error)
+}
+
+// counts tokens of a message list, used by the token based strategies
+type TokenCountFunc func(messages []Message) int
+
+// rough token estimate used when no counter is configured (about 4 characters per token)
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		chars := len(m.Role) + len(m.Content)
+		for _, tc := range m.ToolCalls {
+			chars += len(tc.Name) + len(tc.Arguments)
+		}
+		total += chars/4 + 4
+	}
+	return total
+}
+
+// drops the oldest messages until the history fits in MaxTokens
+// system messages and the latest turn are always kept
+type TokenWindow struct {
+	MaxTokens int
+	Count     TokenCountFunc // defaults to EstimateTokens
+}
+
+func (w *TokenWindow) Apply(ctx context.Context, messages []Message, opts *Options) ([]Message, error) {
+	count := w.Count
+	if count == nil {
+		count = EstimateTokens
+	}
+	if w.MaxTokens <= 0 || count(messages) <= w.MaxTokens {
+		return messages, nil
+	}
+
+	system, groups := groupHistory(messages)
+	for len(groups) > 1 && count(joinHistory(system, groups)) > w.MaxTokens {
+		groups = groups[1:]
+	}
+	return dropLeadingNonUser(system, groups), nil
+}
+
+// keeps the system messages and the last N user turns
+type LastTurns struct {
+	N int
+}
+
+func (l *LastTurns) Apply(ctx context.Context, messages []Message, opts *Options) ([]Message, error) {
+	if l.N <= 0 {
+		return messages, nil
+	}
+
+	system, groups := groupHistory(messages)
+	start := turnStart(groups, l.N)
+	return joinHistory(system, groups[start:]), nil
+}
+
+// replaces older turns with a summary produced by an extra completion call
+// summarizing only happens once the history is larger than MaxTokens
+// the summary is sent as a user message acknowledged by the assistant, so the system prompt is kept
+// the last summary is reused while the summarized turns stay the same, e.g. across tool steps
+type Summarizer struct {
+	Provider  Provider
+	Model     string
+	MaxTokens int            // history size that triggers summarizing
+	KeepTurns int            // most recent turns kept verbatim, defaults to 2
+	Count     TokenCountFunc // defaults to EstimateTokens
+	Prompt    string         // instruction for the summary call
+
+	mu      sync.Mutex
+	lastKey [sha256.Size]byte
+	last    string
+}
+
+const defaultSummaryPrompt = "Summarize the following conversation. Keep facts, decisions, names and open questions. Reply with the summary only."
+
+func (s *Summarizer) Apply(ctx context.Context, messages []Message, opts *Options) ([]Message, error) {
+	count := s.Count
+	if count == nil {
+		count = EstimateTokens
+	}
+	if s.MaxTokens <= 0 || count(messages) <= s.MaxTokens {
+		return messages, nil
+	}
+	if s.Provider == nil {
+		return nil, errors.New("summarizing history: Summarizer.Provider is not set")
+	}
+
+	keep := s.KeepTurns
+	if keep <= 0 {
+		keep = 2
+	}
+
+	system, groups := groupHistory(messages)
+	start := turnStart(groups, keep)
+	if start == 0 {
+		return messages, nil
+	}
+
+	var transcript strings.Builder
+	for _, g := range groups[:start] {
+		for _, m := range g {
+			writeTranscriptLine(&transcript, m)
+		}
+	}
+
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = defaultSummaryPrompt
+	}
+
+	model := s.Model
+	if model == "" && opts != nil {
+		model = opts.Model
+	}
+
+	summary, err := s.summarize(ctx, prompt, model, transcript.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return joinHistory(append(system,
+		Message{Role: "user", Content: "Summary of the earlier conversation:\n" + summary},
+		Message{Role: "assistant", Content: "Understood."},
+	), groups[start:]), nil
+}
+
+// returns the cached summary when the transcript is unchanged
+func (s *Summarizer) summarize(ctx context.Context, prompt, model, transcript string) (string, error) {
+	key := sha256.Sum256([]byte(model + "\x00" + prompt + "\x00" + transcript))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last != "" && s.lastKey == key {
+		return s.last, nil
+	}
+
+	resp, err := s.Provider.CreateCompletion(ctx, []Message{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: transcript},
+	}, &Options{Model: model})
+	if err != nil {
+		return "", fmt.Errorf("summarizing history: %w", err)
+	}
+
+	s.lastKey, s.last = key, resp.Content
+	return resp.Content, nil
+}
+
+func writeTranscriptLine(b *strings.Builder, m Message) {
+	if m.Content != "" {
+		fmt.Fprintf(b, "%s: %s\n", m.Role, m.Content)
+	}
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(b, "%s called %s(%s)\n", m.Role, tc.Name, tc.Arguments)
+	}
+}
+
+// splits the history into leading system messages and atomic groups
+// an assistant message with tool calls is grouped with the tool results that follow it
+func groupHistory(messages []Message) ([]Message, [][]Message) {
+	var system []Message
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		system = append(system, messages[i])
+		i++
+	}
+
+	var groups [][]Message
+	for i < len(messages) {
+		group := []Message{messages[i]}
+		if len(messages[i].ToolCalls) > 0 || messages[i].Role == "tool" {
+			for i+1 < len(messages) && messages[i+1].Role == "tool" {
+				i++
+				group = append(group, messages[i])
+			}
+		}
+		groups = append(groups, group)
+		i++
+	}
+	return system, groups
+}
+
+// index of the group where the nth most recent user turn starts
+func turnStart(groups [][]Message, n int) int {
+	seen := 0
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i][0].Role == "user" {
+			seen++
+			if seen == n {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+func joinHistory(system []Message, groups [][]Message) []Message {
+	out := append([]Message{}, system...)
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+// avoids starting the window in the middle of a turn when a user message is still available
+func dropLeadingNonUser(system []Message, groups [][]Message) []Message {
+	for i, g := range groups {
+		if g[0].Role == "user" {
+			return joinHistory(system, groups[i:])
+		}
+	}
+	return joinHistory(system, groups)
+}
+
+// applies the configured history strategy, if any
+func (sdk *SDK) applyHistory(ctx context.Context, messages []Message, opts *Options) ([]Message, error) {
+	if opts == nil || opts.History == nil {
+		return messages, nil
+	}
+
+	applied, err := opts.History.Apply(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) != len(messages) {
+		Logger(ctx).Debug("history rewritten", "messages", len(messages), "kept", len(applied))
+	}
+	return applied, nil
+}
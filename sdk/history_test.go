@@ -0,0 +1,87 @@
+package sdk_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func longHistory() []sdk.Message {
+	return []sdk.Message{
+		{Role: "user", Content: "first question"},
+		{Role: "assistant", Content: "first answer"},
+		{Role: "user", Content: "second question"},
+		{Role: "assistant", Content: "second answer"},
+	}
+}
+
+func TestSummarizerKeepsSystemPrompt(t *testing.T) {
+	summarizer, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("they asked two questions", 10, 5)
+	})
+	provider, main := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("third answer", 10, 5)
+	})
+
+	client := sdk.NewSDK(provider)
+	conv := client.NewConversation(&sdk.CompletionRequest{
+		SystemPrompt: "be concise",
+		Messages:     longHistory(),
+		History:      &sdk.Summarizer{Provider: summarizer, MaxTokens: 1, KeepTurns: 1},
+	})
+
+	if resp := conv.Send(context.Background(), "third question"); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+
+	sent := main.requests[0]
+	if sent[0].Role != "system" || sent[0].Content != "be concise" {
+		t.Fatalf("system prompt lost, first message is %+v", sent[0])
+	}
+	if sent[1].Role != "user" || !strings.Contains(sent[1].Content, "they asked two questions") {
+		t.Fatalf("summary not sent as a user message: %+v", sent[1])
+	}
+	if last := sent[len(sent)-1]; last.Content != "third question" {
+		t.Fatalf("latest turn not kept: %+v", last)
+	}
+}
+
+func TestSummarizerReusesSummaryAcrossToolSteps(t *testing.T) {
+	summarizer, summaries := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("summary", 10, 5)
+	})
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if n == 0 {
+			return toolReply("call-1", "lookup", 10, 5)
+		}
+		return textReply("done", 10, 5)
+	})
+
+	resp := sdk.NewSDK(provider).ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: append(longHistory(), sdk.Message{Role: "user", Content: "third question"}),
+		Tools:    echoTool(),
+		History:  &sdk.Summarizer{Provider: summarizer, MaxTokens: 1, KeepTurns: 1},
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if got := summaries.calls(); got != 1 {
+		t.Fatalf("summary calls = %d, want 1", got)
+	}
+}
+
+func TestSummarizerWithoutProvider(t *testing.T) {
+	provider, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		return textReply("answer", 10, 5)
+	})
+
+	resp := sdk.NewSDK(provider).ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: append(longHistory(), sdk.Message{Role: "user", Content: "third question"}),
+		History:  &sdk.Summarizer{MaxTokens: 1},
+	})
+	if resp.Error == nil {
+		t.Fatal("expected an error without a summary provider")
+	}
+}
@@ -0,0 +1,50 @@
+// image generation
+
+package sdk
+
+import "context"
+
+type ImageFormat string
+
+const (
+	ImageFormatURL    ImageFormat = "url"
+	ImageFormatBase64 ImageFormat = "b64_json"
+)
+
+type ImageRequest struct {
+	Model          string      // defaults to the provider's image model
+	Prompt         string      // description of the image
+	Size           string      // e.g. "1024x1024", not supported by every provider
+	N              int         // number of images, defaults to 1
+	Quality        string      // e.g. "standard", "hd", "high", provider specific
+	ResponseFormat ImageFormat // URL or inline bytes, providers without URLs always return bytes
+}
+
+// a generated image, either inline bytes or a URL
+type Image struct {
+	Data          []byte // set for base64 responses
+	MIMEType      string // set with Data
+	URL           string // set for URL responses
+	RevisedPrompt string // prompt rewritten by the provider, if any
+}
+
+type ImageResponse struct {
+	Images []Image
+	Model  string
+	Usage  Usage
+}
+
+// implemented by providers that can generate images
+type ImageGenerator interface {
+	GenerateImage(ctx context.Context, req *ImageRequest) (*ImageResponse, error)
+}
+
+// generates images from a prompt
+// returns ErrUnsupported when the provider cannot generate images
+func (sdk *SDK) GenerateImage(ctx context.Context, req *ImageRequest) (*ImageResponse, error) {
+	generator, ok := capability[ImageGenerator](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return generator.GenerateImage(ctx, req)
+}
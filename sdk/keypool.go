@@ -0,0 +1,252 @@
+// multi-key load balancing and rotation
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// returned when every key in the pool is cooling down
+var ErrAllKeysQuarantined = errors.New("keypool: all keys are quarantined")
+
+// returned when the pool has no keys
+var ErrNoKeys = errors.New("keypool: no keys")
+
+type PoolKey struct {
+	APIKey       string
+	Organization string // passed to the factory, e.g. for the OpenAI organization header
+	Weight       int    // used by the Weighted strategy, defaults to 1
+}
+
+type KeyStrategy int
+
+const (
+	RoundRobin             KeyStrategy = iota // keys in turn
+	LeastRecentlyThrottled                    // the key whose last 401/429 is the oldest
+	Weighted                                  // smooth weighted round robin
+)
+
+// spreads requests over a pool of keys
+// keys answering 401 or 429 are quarantined for Cooldown (or Retry-After) and the request moves to the next key
+type KeyPoolProvider struct {
+	Factory  func(key PoolKey) Provider
+	Strategy KeyStrategy
+	Cooldown time.Duration // defaults to one minute
+
+	mu   sync.Mutex
+	keys []*pooledKey
+	next int
+}
+
+type pooledKey struct {
+	PoolKey
+	provider      Provider
+	cooldownUntil time.Time
+	lastThrottled time.Time
+	current       int // weighted round robin state
+}
+
+func NewKeyPoolProvider(factory func(key PoolKey) Provider, strategy KeyStrategy, keys ...PoolKey) *KeyPoolProvider {
+	p := &KeyPoolProvider{
+		Factory:  factory,
+		Strategy: strategy,
+		Cooldown: time.Minute,
+	}
+	p.SetKeys(keys...)
+	return p
+}
+
+// replaces the pool, keys already present keep their providers and cooldowns
+func (p *KeyPoolProvider) SetKeys(keys ...PoolKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[PoolKey]*pooledKey, len(p.keys))
+	for _, k := range p.keys {
+		existing[k.PoolKey] = k
+	}
+
+	pooled := make([]*pooledKey, 0, len(keys))
+	for _, key := range keys {
+		if k, ok := existing[key]; ok {
+			pooled = append(pooled, k)
+			continue
+		}
+		pooled = append(pooled, &pooledKey{PoolKey: key, provider: p.Factory(key)})
+	}
+	p.keys = pooled
+	p.next = 0
+}
+
+func (p *KeyPoolProvider) AddKey(key PoolKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, &pooledKey{PoolKey: key, provider: p.Factory(key)})
+}
+
+// removes every pool entry using apiKey
+func (p *KeyPoolProvider) RemoveKey(apiKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kept := p.keys[:0]
+	for _, k := range p.keys {
+		if k.APIKey != apiKey {
+			kept = append(kept, k)
+		}
+	}
+	p.keys = kept
+	p.next = 0
+}
+
+// name of the first key's provider, without picking a key
+func (p *KeyPoolProvider) Name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) == 0 {
+		return ""
+	}
+	return ProviderName(p.keys[0].provider)
+}
+
+// returns the provider of the next available key, nil when none is available
+// capability calls such as Embed are spread over the keys but don't quarantine them
+func (p *KeyPoolProvider) Unwrap() Provider {
+	key, err := p.pick()
+	if err != nil {
+		return nil
+	}
+	return key.provider
+}
+
+func (p *KeyPoolProvider) CreateCompletion(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt < p.size(); attempt++ {
+		key, err := p.pick()
+		if err != nil {
+			return nil, errors.Join(err, lastErr)
+		}
+
+		resp, err := key.provider.CreateCompletion(ctx, messages, opts)
+		if err == nil || !p.quarantine(key, err) {
+			return resp, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (p *KeyPoolProvider) CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+	var lastErr error
+	for attempt := 0; attempt < p.size(); attempt++ {
+		key, err := p.pick()
+		if err != nil {
+			return nil, errors.Join(err, lastErr)
+		}
+
+		stream, err := key.provider.CreateCompletionStream(ctx, messages, opts)
+		if err == nil || !p.quarantine(key, err) {
+			return stream, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (p *KeyPoolProvider) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return max(len(p.keys), 1)
+}
+
+// selects the next available key according to the strategy
+func (p *KeyPoolProvider) pick() (*pooledKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	now := time.Now()
+	available := make([]*pooledKey, 0, len(p.keys))
+	for _, k := range p.keys {
+		if !now.Before(k.cooldownUntil) {
+			available = append(available, k)
+		}
+	}
+	if len(available) == 0 {
+		return nil, ErrAllKeysQuarantined
+	}
+
+	switch p.Strategy {
+	case LeastRecentlyThrottled:
+		best := available[0]
+		for _, k := range available[1:] {
+			if k.lastThrottled.Before(best.lastThrottled) {
+				best = k
+			}
+		}
+		// rotate among keys that were never throttled
+		if best.lastThrottled.IsZero() {
+			return p.roundRobin(available, func(k *pooledKey) bool { return k.lastThrottled.IsZero() }), nil
+		}
+		return best, nil
+
+	case Weighted:
+		total := 0
+		var best *pooledKey
+		for _, k := range available {
+			weight := max(k.Weight, 1)
+			total += weight
+			k.current += weight
+			if best == nil || k.current > best.current {
+				best = k
+			}
+		}
+		best.current -= total
+		return best, nil
+
+	default:
+		return p.roundRobin(available, nil), nil
+	}
+}
+
+func (p *KeyPoolProvider) roundRobin(available []*pooledKey, eligible func(*pooledKey) bool) *pooledKey {
+	for range available {
+		k := available[p.next%len(available)]
+		p.next++
+		if eligible == nil || eligible(k) {
+			return k
+		}
+	}
+	return available[0]
+}
+
+// puts a key on cooldown after a 401 or 429, reports whether it did
+// a 403 is left alone, it usually means the request (e.g. the model) is not allowed rather than a bad key
+func (p *KeyPoolProvider) quarantine(key *pooledKey, err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || (apiErr.StatusCode != 401 && apiErr.StatusCode != 429) {
+		return false
+	}
+
+	cooldown := p.Cooldown
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+	if retry := apiErr.RetryAfter(); retry > 0 {
+		cooldown = retry
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	key.lastThrottled = now
+	key.cooldownUntil = now.Add(cooldown)
+	return true
+}
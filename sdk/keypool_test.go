@@ -0,0 +1,60 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// pool whose keys fail with the given status codes, 0 succeeds
+func statusPool(statuses map[string]int, calls map[string]int) *sdk.KeyPoolProvider {
+	factory := func(key sdk.PoolKey) sdk.Provider {
+		return &sdk.ProviderFuncs{
+			Completion: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (*sdk.CompletionResponse, error) {
+				calls[key.APIKey]++
+				if status := statuses[key.APIKey]; status != 0 {
+					return nil, &sdk.APIError{StatusCode: status}
+				}
+				return &sdk.CompletionResponse{Content: key.APIKey}, nil
+			},
+		}
+	}
+	return sdk.NewKeyPoolProvider(factory, sdk.RoundRobin, sdk.PoolKey{APIKey: "a"}, sdk.PoolKey{APIKey: "b"})
+}
+
+func TestKeyPoolQuarantinesUnauthorizedKeys(t *testing.T) {
+	calls := map[string]int{}
+	pool := statusPool(map[string]int{"a": 401}, calls)
+
+	for range 3 {
+		resp, err := pool.CreateCompletion(context.Background(), nil, nil)
+		if err != nil || resp.Content != "b" {
+			t.Fatalf("resp = %v, err = %v", resp, err)
+		}
+	}
+	if calls["a"] != 1 {
+		t.Errorf("quarantined key called %d times, want 1", calls["a"])
+	}
+}
+
+func TestKeyPoolKeepsForbiddenKeys(t *testing.T) {
+	calls := map[string]int{}
+	pool := statusPool(map[string]int{"a": 403}, calls)
+
+	for range 4 {
+		pool.CreateCompletion(context.Background(), nil, nil)
+	}
+	if calls["a"] != 2 || calls["b"] != 2 {
+		t.Errorf("calls = %v, a 403 must not quarantine the key", calls)
+	}
+}
+
+func TestKeyPoolWithoutKeys(t *testing.T) {
+	pool := sdk.NewKeyPoolProvider(func(key sdk.PoolKey) sdk.Provider { return nil }, sdk.RoundRobin)
+
+	if _, err := pool.CreateCompletion(context.Background(), nil, nil); !errors.Is(err, sdk.ErrNoKeys) {
+		t.Fatalf("err = %v, want ErrNoKeys", err)
+	}
+}
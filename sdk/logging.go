@@ -0,0 +1,60 @@
+// structured logging with log/slog
+
+package sdk
+
+import (
+	"context"
+	"log/slog"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logs request metadata, retries, tool steps and errors
+func WithLogger(logger *slog.Logger) SDKOption {
+	return func(s *SDK) {
+		s.logger = logger
+	}
+}
+
+// also logs request and response bodies at debug level, API keys are never logged
+func WithPayloadLogging() SDKOption {
+	return func(s *SDK) {
+		s.logPayloads = true
+	}
+}
+
+type loggerKey struct{}
+
+type logConfig struct {
+	logger   *slog.Logger
+	payloads bool
+}
+
+// attaches a logger to ctx, picked up by providers and middlewares
+func ContextWithLogger(ctx context.Context, logger *slog.Logger, payloads bool) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logConfig{logger: logger, payloads: payloads})
+}
+
+// returns the logger attached to ctx, a discarding logger when there is none
+func Logger(ctx context.Context) *slog.Logger {
+	if cfg, ok := ctx.Value(loggerKey{}).(logConfig); ok && cfg.logger != nil {
+		return cfg.logger
+	}
+	return discardLogger
+}
+
+// reports whether payload logging was enabled for ctx
+func LogPayloads(ctx context.Context) bool {
+	cfg, _ := ctx.Value(loggerKey{}).(logConfig)
+	return cfg.payloads
+}
+
+func (sdk *SDK) withLogger(ctx context.Context) context.Context {
+	if sdk.logger == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(loggerKey{}).(logConfig); ok {
+		return ctx
+	}
+	return ContextWithLogger(ctx, sdk.logger, sdk.logPayloads)
+}
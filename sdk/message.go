@@ -1,14 +1,64 @@
 package sdk
 
 type Message struct {
-	Role       string            `json:"role"`
-	Content    string            `json:"content"`
-	ToolCallID string            `json:"tool_call_id,omitempty"`
-	ToolCalls  []ToolCallRequest `json:"tool_calls,omitempty"`
+	Role         string            `json:"role"`
+	Content      string            `json:"content"`
+	ToolCallID   string            `json:"tool_call_id,omitempty"`
+	ToolCalls    []ToolCallRequest `json:"tool_calls,omitempty"`
+	CacheControl *CacheControl     `json:"cache_control,omitempty"` // prompt cache breakpoint after this message
+
+	// model reasoning of assistant messages, the blocks are sent back unchanged on later turns
+	Reasoning       string           `json:"reasoning,omitempty"`
+	ReasoningBlocks []ReasoningBlock `json:"reasoning_blocks,omitempty"`
+}
+
+// a signed reasoning block (Anthropic thinking or redacted_thinking) that must be preserved across tool turns
+type ReasoningBlock struct {
+	Type      string `json:"type"`
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // encrypted content of redacted blocks
+}
+
+// marks the end of a cacheable prompt prefix, used by providers with explicit prompt caching (Anthropic)
+type CacheControl struct {
+	TTL string `json:"ttl,omitempty"` // e.g. "5m" or "1h", empty for the provider default
 }
 
 type CompletionResponse struct {
-	Content   string
-	ToolCalls []ToolCallRequest
-	Role      string
+	Content         string
+	Reasoning       string
+	ReasoningBlocks []ReasoningBlock
+	Citations       []Citation
+	Images          []SearchImage // found by the search, e.g. Perplexity with ReturnImages
+	ToolCalls       []ToolCallRequest
+	Role            string
+	Provider        string // backend that served the request, set by wrapping providers
+	Model           string
+	FinishReason    string
+	Usage           Usage
+	FromCache       bool // served by CacheProvider, Usage is zero since no tokens were spent
+}
+
+// token counts reported by the provider
+// input tokens include cached and cache creation ones, output tokens include reasoning ones
+type Usage struct {
+	InputTokens         int `json:"input_tokens,omitempty"`
+	OutputTokens        int `json:"output_tokens,omitempty"`
+	TotalTokens         int `json:"total_tokens,omitempty"`
+	CachedInputTokens   int `json:"cached_input_tokens,omitempty"`   // read from the prompt cache
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"` // written to the prompt cache
+	ReasoningTokens     int `json:"reasoning_tokens,omitempty"`
+}
+
+// sums the counts of both usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:         u.InputTokens + other.InputTokens,
+		OutputTokens:        u.OutputTokens + other.OutputTokens,
+		TotalTokens:         u.TotalTokens + other.TotalTokens,
+		CachedInputTokens:   u.CachedInputTokens + other.CachedInputTokens,
+		CacheCreationTokens: u.CacheCreationTokens + other.CacheCreationTokens,
+		ReasoningTokens:     u.ReasoningTokens + other.ReasoningTokens,
+	}
 }
@@ -0,0 +1,227 @@
+// middleware chain around providers
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+)
+
+// wraps a provider, e.g. for logging, metrics, retries or redaction
+type Middleware func(Provider) Provider
+
+type SDKOption func(*SDK)
+
+// wraps the provider in middlewares, the first one is the outermost
+func WithMiddleware(middlewares ...Middleware) SDKOption {
+	return func(s *SDK) {
+		s.middlewares = append(s.middlewares, middlewares...)
+	}
+}
+
+// adapts a pair of functions to the Provider interface
+// nil functions fall through to Next
+type ProviderFuncs struct {
+	Next       Provider
+	Completion func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error)
+	Stream     func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error)
+}
+
+func (f *ProviderFuncs) CreateCompletion(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+	if f.Completion == nil {
+		return f.Next.CreateCompletion(ctx, messages, opts)
+	}
+	return f.Completion(ctx, messages, opts)
+}
+
+func (f *ProviderFuncs) Unwrap() Provider {
+	return f.Next
+}
+
+func (f *ProviderFuncs) CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+	if f.Stream == nil {
+		return f.Next.CreateCompletionStream(ctx, messages, opts)
+	}
+	return f.Stream(ctx, messages, opts)
+}
+
+// summary of a provider call passed to Hook
+type Call struct {
+	Messages []Message
+	Options  *Options
+	Stream   bool
+	Response *CompletionResponse // nil for streams
+	Bytes    int                 // streamed bytes
+	Err      error
+	Duration time.Duration // for streams, until the stream ends or is closed
+}
+
+// calls fn after every completion and once every stream has ended
+func Hook(fn func(ctx context.Context, call *Call)) Middleware {
+	return func(next Provider) Provider {
+		return &ProviderFuncs{
+			Next: next,
+			Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+				start := time.Now()
+				resp, err := next.CreateCompletion(ctx, messages, opts)
+				fn(ctx, &Call{Messages: messages, Options: opts, Response: resp, Err: err, Duration: time.Since(start)})
+				return resp, err
+			},
+			Stream: func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+				start := time.Now()
+				stream, err := next.CreateCompletionStream(ctx, messages, opts)
+				if err != nil {
+					fn(ctx, &Call{Messages: messages, Options: opts, Stream: true, Err: err, Duration: time.Since(start)})
+					return nil, err
+				}
+				return &observedStream{ReadCloser: stream, onEnd: func(n int, err error) {
+					fn(ctx, &Call{Messages: messages, Options: opts, Stream: true, Bytes: n, Err: err, Duration: time.Since(start)})
+				}}, nil
+			},
+		}
+	}
+}
+
+// retries transient errors (5xx, 429, timeouts, network) with exponential backoff
+// a Retry-After header takes precedence over the backoff, streams are only retried until they are opened
+func Retry(maxAttempts int, backoff time.Duration) Middleware {
+	return func(next Provider) Provider {
+		return &ProviderFuncs{
+			Next: next,
+			Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+				var resp *CompletionResponse
+				err := retry(ctx, maxAttempts, backoff, func() (err error) {
+					resp, err = next.CreateCompletion(ctx, messages, opts)
+					return err
+				})
+				return resp, err
+			},
+			Stream: func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+				var stream io.ReadCloser
+				err := retry(ctx, maxAttempts, backoff, func() (err error) {
+					stream, err = next.CreateCompletionStream(ctx, messages, opts)
+					return err
+				})
+				return stream, err
+			},
+		}
+	}
+}
+
+func retry(ctx context.Context, maxAttempts int, backoff time.Duration, call func() error) error {
+	var err error
+	delay := backoff
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt >= maxAttempts || ClassifyError(err)&ErrorTransient == 0 {
+			return err
+		}
+
+		wait := delay
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter() > 0 {
+			wait = apiErr.RetryAfter()
+		}
+		delay *= 2
+
+		Logger(ctx).Warn("retrying request", "attempt", attempt, "max_attempts", maxAttempts, "wait", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// replaces matches of the patterns in outgoing message contents and the system prompt
+func Redact(replacement string, patterns ...*regexp.Regexp) Middleware {
+	redact := func(s string) string {
+		for _, re := range patterns {
+			s = re.ReplaceAllString(s, replacement)
+		}
+		return s
+	}
+	redactRequest := func(messages []Message, opts *Options) ([]Message, *Options) {
+		redacted := make([]Message, len(messages))
+		for i, m := range messages {
+			m.Content = redact(m.Content)
+			redacted[i] = m
+		}
+		if opts != nil && opts.SystemPrompt != "" {
+			o := *opts
+			o.SystemPrompt = redact(o.SystemPrompt)
+			opts = &o
+		}
+		return redacted, opts
+	}
+
+	return func(next Provider) Provider {
+		return &ProviderFuncs{
+			Next: next,
+			Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+				messages, opts = redactRequest(messages, opts)
+				return next.CreateCompletion(ctx, messages, opts)
+			},
+			Stream: func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+				messages, opts = redactRequest(messages, opts)
+				return next.CreateCompletionStream(ctx, messages, opts)
+			},
+		}
+	}
+}
+
+// RateLimitProvider as a middleware
+func RateLimiting(limit RateLimit) Middleware {
+	return func(next Provider) Provider {
+		return NewRateLimitProvider(next, limit)
+	}
+}
+
+// CacheProvider as a middleware
+func Caching(store CacheStore, ttl time.Duration) Middleware {
+	return func(next Provider) Provider {
+		return NewCacheProvider(next, store, ttl)
+	}
+}
+
+// reports the first bytes, and the number of bytes read and the final error once at EOF, on error or on close
+type observedStream struct {
+	io.ReadCloser
+	n       int
+	onFirst func() // optional
+	onEnd   func(n int, err error)
+	ended   bool
+}
+
+func (s *observedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 && s.n == 0 && s.onFirst != nil {
+		s.onFirst()
+	}
+	s.n += n
+	if err == io.EOF {
+		s.end(nil)
+	} else if err != nil {
+		s.end(err)
+	}
+	return n, err
+}
+
+func (s *observedStream) Close() error {
+	err := s.ReadCloser.Close()
+	s.end(nil)
+	return err
+}
+
+func (s *observedStream) end(err error) {
+	if !s.ended {
+		s.ended = true
+		s.onEnd(s.n, err)
+	}
+}
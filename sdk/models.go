@@ -0,0 +1,236 @@
+// model catalog with capabilities and limits, used to validate requests
+
+package sdk
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Capability string
+
+const (
+	CapabilityTools       Capability = "tools"
+	CapabilityVision      Capability = "vision"
+	CapabilityJSON        Capability = "json"        // JSON mode / structured output
+	CapabilityReasoning   Capability = "reasoning"   // accepts ReasoningEffort
+	CapabilityTemperature Capability = "temperature" // accepts Temperature
+)
+
+type ModelSpec struct {
+	Capabilities    []Capability `json:"capabilities"`
+	ContextWindow   int          `json:"context_window,omitempty"`
+	MaxOutputTokens int          `json:"max_output_tokens,omitempty"`
+	Deprecated      string       `json:"deprecated,omitempty"`  // shutdown date, YYYY-MM-DD
+	Replacement     string       `json:"replacement,omitempty"` // suggested model once deprecated
+}
+
+func (s ModelSpec) Has(c Capability) bool {
+	return slices.Contains(s.Capabilities, c)
+}
+
+// reports whether the deprecation date has passed at t
+func (s ModelSpec) DeprecatedAt(t time.Time) bool {
+	if s.Deprecated == "" {
+		return false
+	}
+	date, err := time.Parse(time.DateOnly, s.Deprecated)
+	return err == nil && !t.Before(date)
+}
+
+// maps provider and model names to specs, safe for concurrent use
+// lookups match dated snapshots like PriceTable does
+type ModelCatalog struct {
+	mu     sync.RWMutex
+	models map[string]map[string]ModelSpec // provider -> model -> spec
+}
+
+//go:embed models.json
+var defaultModels []byte
+
+// catalog used when no other one is configured, loaded from the embedded models.json
+var DefaultModels = mustLoadDefaultModels()
+
+func mustLoadDefaultModels() *ModelCatalog {
+	c := NewModelCatalog()
+	if err := json.Unmarshal(defaultModels, &c.models); err != nil {
+		panic(fmt.Sprintf("sdk: invalid embedded model catalog: %v", err))
+	}
+	return c
+}
+
+func NewModelCatalog() *ModelCatalog {
+	return &ModelCatalog{models: map[string]map[string]ModelSpec{}}
+}
+
+func (c *ModelCatalog) Register(provider, model string, spec ModelSpec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.models[provider] == nil {
+		c.models[provider] = map[string]ModelSpec{}
+	}
+	c.models[provider][model] = spec
+}
+
+// merges specs in the models.json format ({"provider": {"model": {...}}}), overriding existing entries
+func (c *ModelCatalog) Load(r io.Reader) error {
+	var models map[string]map[string]ModelSpec
+	if err := json.NewDecoder(r).Decode(&models); err != nil {
+		return fmt.Errorf("decoding model catalog: %w", err)
+	}
+	for provider, specs := range models {
+		for model, spec := range specs {
+			c.Register(provider, model, spec)
+		}
+	}
+	return nil
+}
+
+func (c *ModelCatalog) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f)
+}
+
+// finds the spec of a model, searching every provider when the given one has no match
+func (c *ModelCatalog) Lookup(provider, model string) (ModelSpec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if spec, ok := lookupModel(c.models[provider], model); ok {
+		return spec, true
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.models)) {
+		if spec, ok := lookupModel(c.models[name], model); ok {
+			return spec, true
+		}
+	}
+	return ModelSpec{}, false
+}
+
+// how ChatCompletion treats parameters the model does not support
+type ValidationMode int
+
+const (
+	ValidateLenient ValidationMode = iota // drops unsupported parameters with a warning
+	ValidateStrict                        // rejects the request with ErrUnsupportedParameter
+	ValidateOff
+)
+
+// uses the given catalog instead of DefaultModels to validate requests
+func WithModelCatalog(catalog *ModelCatalog) SDKOption {
+	return func(s *SDK) {
+		s.models = catalog
+	}
+}
+
+func WithValidation(mode ValidationMode) SDKOption {
+	return func(s *SDK) {
+		s.validation = mode
+	}
+}
+
+// checks the options against the model catalog, dropping or rejecting unsupported parameters
+// models missing from the catalog are not validated
+func (sdk *SDK) validate(ctx context.Context, messages []Message, opts *Options) error {
+	if sdk.validation == ValidateOff {
+		return nil
+	}
+	catalog := sdk.models
+	if catalog == nil {
+		catalog = DefaultModels
+	}
+	spec, ok := catalog.Lookup(ProviderName(sdk.base), opts.Model)
+	if !ok {
+		return nil
+	}
+
+	logger := Logger(ctx)
+	strict := sdk.validation == ValidateStrict
+	unsupported := func(param string) error {
+		if strict {
+			return fmt.Errorf("%s: %s: %w", opts.Model, param, ErrUnsupportedParameter)
+		}
+		logger.Warn("dropping unsupported parameter", "model", opts.Model, "parameter", param)
+		return nil
+	}
+
+	if opts.Temperature != 0 && !spec.Has(CapabilityTemperature) {
+		if err := unsupported("temperature"); err != nil {
+			return err
+		}
+		opts.Temperature = 0
+	}
+	if opts.ReasoningEffort != "" && !spec.Has(CapabilityReasoning) {
+		if err := unsupported("reasoning effort"); err != nil {
+			return err
+		}
+		opts.ReasoningEffort = ""
+	}
+	if opts.ThinkingBudget > 0 && !spec.Has(CapabilityReasoning) {
+		if err := unsupported("thinking budget"); err != nil {
+			return err
+		}
+		opts.ThinkingBudget = 0
+	}
+	if spec.MaxOutputTokens > 0 && opts.MaxCompletionTokens > spec.MaxOutputTokens {
+		if strict {
+			return fmt.Errorf("%s: max tokens %d above the limit of %d: %w", opts.Model, opts.MaxCompletionTokens, spec.MaxOutputTokens, ErrUnsupportedParameter)
+		}
+		logger.Warn("clamping max tokens", "model", opts.Model, "max_tokens", opts.MaxCompletionTokens, "limit", spec.MaxOutputTokens)
+		opts.MaxCompletionTokens = spec.MaxOutputTokens
+	}
+
+	// the request would fail at the provider, there is no sensible parameter to drop
+	if len(opts.Tools) > 0 && !spec.Has(CapabilityTools) {
+		if strict {
+			return fmt.Errorf("%s: tools: %w", opts.Model, ErrUnsupportedParameter)
+		}
+		logger.Warn("model does not support tools", "model", opts.Model)
+	}
+	if spec.ContextWindow > 0 && EstimateTokens(messages) > spec.ContextWindow {
+		logger.Warn("history probably exceeds the context window", "model", opts.Model, "context_window", spec.ContextWindow)
+	}
+	if spec.DeprecatedAt(time.Now()) {
+		logger.Warn("model is deprecated", "model", opts.Model, "since", spec.Deprecated, "replacement", spec.Replacement)
+	}
+	return nil
+}
+
+// model returned by a provider's models endpoint
+type ModelInfo struct {
+	ID            string
+	DisplayName   string
+	Provider      string
+	OwnedBy       string
+	ContextLength int         // zero when the provider does not report it
+	MaxOutput     int         // zero when the provider does not report it
+	Pricing       *ModelPrice // only for providers reporting prices, e.g. OpenRouter
+	Created       time.Time
+}
+
+// implemented by providers with a models endpoint
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// lists the models available to the provider, following pagination
+// returns ErrUnsupported when the provider has no models endpoint
+func (sdk *SDK) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	lister, ok := capability[ModelLister](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return lister.ListModels(ctx)
+}
@@ -0,0 +1,215 @@
+// content moderation
+
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// provider independent moderation category
+type ModerationCategory string
+
+const (
+	ModerationSexual       ModerationCategory = "sexual"
+	ModerationSexualMinors ModerationCategory = "sexual_minors"
+	ModerationHate         ModerationCategory = "hate"
+	ModerationHarassment   ModerationCategory = "harassment"
+	ModerationSelfHarm     ModerationCategory = "self_harm"
+	ModerationViolence     ModerationCategory = "violence"
+	ModerationIllicit      ModerationCategory = "illicit" // dangerous or criminal content
+	ModerationHealth       ModerationCategory = "health"
+	ModerationFinancial    ModerationCategory = "financial"
+	ModerationLaw          ModerationCategory = "law"
+	ModerationPII          ModerationCategory = "pii"
+)
+
+// moderation verdict for one input
+type ModerationResult struct {
+	Flagged    bool                           // the provider's own verdict
+	Categories []ModerationCategory           // categories flagged by the provider
+	Scores     map[ModerationCategory]float64 // 0 to 1, the highest score of the provider categories mapped to each
+}
+
+// implemented by providers with a moderation endpoint
+type Moderator interface {
+	Moderate(ctx context.Context, inputs []string) ([]ModerationResult, error)
+}
+
+// classifies the inputs, one result per input
+// returns ErrUnsupported when the provider has no moderation endpoint
+func (sdk *SDK) Moderate(ctx context.Context, inputs []string) ([]ModerationResult, error) {
+	moderator, ok := capability[Moderator](sdk.base)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return moderator.Moderate(ctx, inputs)
+}
+
+// builds a result from provider category names, mapping them with categories
+// provider categories missing from the map are ignored
+func NewModerationResult(flagged bool, flags map[string]bool, scores map[string]float64, categories map[string]ModerationCategory) ModerationResult {
+	result := ModerationResult{Flagged: flagged, Scores: map[ModerationCategory]float64{}}
+	for name, score := range scores {
+		if c, ok := categories[name]; ok && score > result.Scores[c] {
+			result.Scores[c] = score
+		}
+	}
+	for name, flag := range flags {
+		if c, ok := categories[name]; ok && flag && !slices.Contains(result.Categories, c) {
+			result.Categories = append(result.Categories, c)
+		}
+	}
+	slices.Sort(result.Categories)
+	return result
+}
+
+type ModerationAction int
+
+const (
+	ModerationBlock ModerationAction = iota // fails the request with a ModerationError
+	ModerationFlag                          // calls OnFlag and lets the request through
+)
+
+// decides which moderation results violate the policy
+// without thresholds the provider's own Flagged verdict is used
+type ModerationPolicy struct {
+	Thresholds map[ModerationCategory]float64 // per category score thresholds
+	Default    float64                        // threshold for categories without one, 0 ignores them
+	Action     ModerationAction
+	OnFlag     func(ctx context.Context, violations []ModerationCategory, result ModerationResult) // optional
+}
+
+// categories of the result violating the policy
+func (p *ModerationPolicy) Violations(result ModerationResult) []ModerationCategory {
+	if len(p.Thresholds) == 0 && p.Default == 0 {
+		if result.Flagged {
+			return append([]ModerationCategory{}, result.Categories...)
+		}
+		return nil
+	}
+
+	var violations []ModerationCategory
+	for c, score := range result.Scores {
+		threshold, ok := p.Thresholds[c]
+		if !ok {
+			threshold = p.Default
+		}
+		if threshold > 0 && score >= threshold {
+			violations = append(violations, c)
+		}
+	}
+	slices.Sort(violations)
+	return violations
+}
+
+// returned by the moderation middleware when a request is blocked
+// wraps ErrContentFiltered
+type ModerationError struct {
+	Violations []ModerationCategory
+	Result     ModerationResult
+}
+
+func (e *ModerationError) Error() string {
+	names := make([]string, len(e.Violations))
+	for i, c := range e.Violations {
+		names[i] = string(c)
+	}
+	return fmt.Sprintf("blocked by moderation: %s", strings.Join(names, ", "))
+}
+
+func (e *ModerationError) Unwrap() error {
+	return ErrContentFiltered
+}
+
+type moderatedKey struct{}
+
+// inputs already moderated during one ChatCompletion, by content hash
+type moderatedInputs struct {
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]bool
+}
+
+// attaches an empty set of moderated inputs, so the steps of a tool loop moderate each input once
+func withModerationMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, moderatedKey{}, &moderatedInputs{seen: map[[sha256.Size]byte]bool{}})
+}
+
+// reports whether input was already moderated in this request, and marks it as moderated
+func moderatedBefore(ctx context.Context, input string) bool {
+	memo, ok := ctx.Value(moderatedKey{}).(*moderatedInputs)
+	if !ok {
+		return false
+	}
+	key := sha256.Sum256([]byte(input))
+
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	if memo.seen[key] {
+		return true
+	}
+	memo.seen[key] = true
+	return false
+}
+
+// moderates the latest user message before it reaches the provider
+// earlier messages are expected to have been moderated in previous turns
+// within a ChatCompletion each distinct input is moderated once, not on every tool step
+func Moderation(moderator Moderator, policy ModerationPolicy) Middleware {
+	check := func(ctx context.Context, messages []Message) error {
+		var input string
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == "user" {
+				input = messages[i].Content
+				break
+			}
+		}
+		if input == "" || moderatedBefore(ctx, input) {
+			return nil
+		}
+
+		results, err := moderator.Moderate(ctx, []string{input})
+		if err != nil {
+			return fmt.Errorf("moderation: %w", err)
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		violations := policy.Violations(results[0])
+		if len(violations) == 0 {
+			return nil
+		}
+		Logger(ctx).Warn("moderation violation", "categories", violations, "action", policy.Action)
+		if policy.Action == ModerationFlag {
+			if policy.OnFlag != nil {
+				policy.OnFlag(ctx, violations, results[0])
+			}
+			return nil
+		}
+		return &ModerationError{Violations: violations, Result: results[0]}
+	}
+
+	return func(next Provider) Provider {
+		return &ProviderFuncs{
+			Next: next,
+			Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+				if err := check(ctx, messages); err != nil {
+					return nil, err
+				}
+				return next.CreateCompletion(ctx, messages, opts)
+			},
+			Stream: func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+				if err := check(ctx, messages); err != nil {
+					return nil, err
+				}
+				return next.CreateCompletionStream(ctx, messages, opts)
+			},
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package sdk_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// Moderator flagging every input as violent and counting the calls
+type countingModerator struct {
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (m *countingModerator) Moderate(ctx context.Context, inputs []string) ([]sdk.ModerationResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, inputs...)
+	results := make([]sdk.ModerationResult, len(inputs))
+	for i := range inputs {
+		results[i] = sdk.ModerationResult{
+			Flagged:    true,
+			Categories: []sdk.ModerationCategory{sdk.ModerationViolence},
+			Scores:     map[sdk.ModerationCategory]float64{sdk.ModerationViolence: 0.9},
+		}
+	}
+	return results, nil
+}
+
+func TestModerationOncePerToolLoop(t *testing.T) {
+	provider, calls := newScripted(func(n int, messages []sdk.Message, stream bool) string {
+		if n < 2 {
+			return toolReply("call", "lookup", 10, 5)
+		}
+		return textReply("done", 10, 5)
+	})
+	moderator := &countingModerator{}
+	flags := 0
+	client := sdk.NewSDK(provider, sdk.WithMiddleware(sdk.Moderation(moderator, sdk.ModerationPolicy{
+		Default: 0.5,
+		Action:  sdk.ModerationFlag,
+		OnFlag: func(ctx context.Context, violations []sdk.ModerationCategory, result sdk.ModerationResult) {
+			flags++
+		},
+	})))
+
+	resp := client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: []sdk.Message{{Role: "user", Content: "hi"}},
+		Tools:    echoTool(),
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if got := calls.calls(); got != 3 {
+		t.Fatalf("made %d provider calls, want 3", got)
+	}
+	if len(moderator.inputs) != 1 || moderator.inputs[0] != "hi" {
+		t.Fatalf("moderated %q, want the input once", moderator.inputs)
+	}
+	if flags != 1 {
+		t.Fatalf("OnFlag called %d times, want 1", flags)
+	}
+
+	// a new request moderates its input again
+	client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Messages: []sdk.Message{{Role: "user", Content: "hi"}},
+	})
+	if len(moderator.inputs) != 2 {
+		t.Fatalf("moderated %d inputs over two requests, want 2", len(moderator.inputs))
+	}
+}
@@ -0,0 +1,195 @@
+// observability hooks for completions, streams and tool calls
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// implemented by providers to report their name, e.g. "openai"
+type Named interface {
+	Name() string
+}
+
+// returns the provider name or "" when it does not report one
+func ProviderName(p Provider) string {
+	if n, ok := capability[Named](p); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+type RequestInfo struct {
+	Provider    string
+	Model       string
+	Stream      bool
+	MaxTokens   int
+	Temperature float32
+	Messages    []Message
+	Start       time.Time
+}
+
+type RequestResult struct {
+	Provider         string // backend that served the request when known
+	Model            string // model reported by the provider
+	FinishReason     string
+	Usage            Usage // for streams, only when the provider reports it
+	Err              error
+	Duration         time.Duration
+	TimeToFirstToken time.Duration // streams only
+	Bytes            int           // streams only
+}
+
+// receives lifecycle events of provider calls and tool executions
+// the start events return the context passed to the matching end events, e.g. to carry a span
+type Observer interface {
+	OnRequestStart(ctx context.Context, info *RequestInfo) context.Context
+	OnRequestEnd(ctx context.Context, info *RequestInfo, result *RequestResult)
+	OnStreamFirstToken(ctx context.Context, info *RequestInfo)
+	OnStreamEnd(ctx context.Context, info *RequestInfo, result *RequestResult)
+	OnToolStart(ctx context.Context, call ToolCallRequest) context.Context
+	OnToolEnd(ctx context.Context, call ToolCallRequest, err error)
+}
+
+// registers observers, they see every provider call and tool execution
+func WithObserver(observers ...Observer) SDKOption {
+	return func(s *SDK) {
+		s.observers = append(s.observers, observers...)
+	}
+}
+
+// fans events out to several observers
+type multiObserver []Observer
+
+func (m multiObserver) OnRequestStart(ctx context.Context, info *RequestInfo) context.Context {
+	for _, o := range m {
+		ctx = o.OnRequestStart(ctx, info)
+	}
+	return ctx
+}
+
+func (m multiObserver) OnRequestEnd(ctx context.Context, info *RequestInfo, result *RequestResult) {
+	for _, o := range m {
+		o.OnRequestEnd(ctx, info, result)
+	}
+}
+
+func (m multiObserver) OnStreamFirstToken(ctx context.Context, info *RequestInfo) {
+	for _, o := range m {
+		o.OnStreamFirstToken(ctx, info)
+	}
+}
+
+func (m multiObserver) OnStreamEnd(ctx context.Context, info *RequestInfo, result *RequestResult) {
+	for _, o := range m {
+		o.OnStreamEnd(ctx, info, result)
+	}
+}
+
+func (m multiObserver) OnToolStart(ctx context.Context, call ToolCallRequest) context.Context {
+	for _, o := range m {
+		ctx = o.OnToolStart(ctx, call)
+	}
+	return ctx
+}
+
+func (m multiObserver) OnToolEnd(ctx context.Context, call ToolCallRequest, err error) {
+	for _, o := range m {
+		o.OnToolEnd(ctx, call, err)
+	}
+}
+
+// middleware firing the request and stream events
+func observing(o Observer, providerName string) Middleware {
+	return func(next Provider) Provider {
+		newInfo := func(messages []Message, opts *Options, stream bool) *RequestInfo {
+			info := &RequestInfo{Provider: providerName, Stream: stream, Messages: messages, Start: time.Now()}
+			if opts != nil {
+				info.Model = opts.Model
+				info.MaxTokens = opts.MaxCompletionTokens
+				info.Temperature = opts.Temperature
+			}
+			return info
+		}
+
+		return &ProviderFuncs{
+			Next: next,
+			Completion: func(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+				info := newInfo(messages, opts, false)
+				ctx = o.OnRequestStart(ctx, info)
+
+				resp, err := next.CreateCompletion(ctx, messages, opts)
+
+				result := &RequestResult{Err: err, Duration: time.Since(info.Start)}
+				if resp != nil {
+					result.Provider = resp.Provider
+					result.Model = resp.Model
+					result.FinishReason = resp.FinishReason
+					result.Usage = resp.Usage
+				}
+				o.OnRequestEnd(ctx, info, result)
+				return resp, err
+			},
+			Stream: func(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+				info := newInfo(messages, opts, true)
+				ctx = o.OnRequestStart(ctx, info)
+
+				// usage events of the provider, if it reports usage for streams
+				var mu sync.Mutex
+				var usage Usage
+				opts = withStreamEvents(opts, func(event StreamEvent) {
+					if event.Type == StreamEventUsage {
+						mu.Lock()
+						usage = event.Usage
+						mu.Unlock()
+					}
+				})
+
+				stream, err := next.CreateCompletionStream(ctx, messages, opts)
+				if err != nil {
+					result := &RequestResult{Err: err, Duration: time.Since(info.Start)}
+					o.OnStreamEnd(ctx, info, result)
+					o.OnRequestEnd(ctx, info, result)
+					return nil, err
+				}
+
+				var firstToken time.Duration
+				return &observedStream{
+					ReadCloser: stream,
+					onFirst: func() {
+						firstToken = time.Since(info.Start)
+						o.OnStreamFirstToken(ctx, info)
+					},
+					onEnd: func(n int, err error) {
+						mu.Lock()
+						final := usage
+						mu.Unlock()
+						result := &RequestResult{
+							Usage:            final,
+							Err:              err,
+							Duration:         time.Since(info.Start),
+							TimeToFirstToken: firstToken,
+							Bytes:            n,
+						}
+						o.OnStreamEnd(ctx, info, result)
+						o.OnRequestEnd(ctx, info, result)
+					},
+				}, nil
+			},
+		}
+	}
+}
+
+// fires the tool events around fn
+func (sdk *SDK) observeTool(ctx context.Context, call ToolCallRequest, fn func(ctx context.Context) error) {
+	if sdk.observer == nil {
+		fn(ctx)
+		return
+	}
+	ctx = sdk.observer.OnToolStart(ctx, call)
+	err := fn(ctx)
+	sdk.observer.OnToolEnd(ctx, call, err)
+}
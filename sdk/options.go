@@ -3,11 +3,18 @@
 package sdk
 
 type Options struct {
-	Model               string          `json:"model,omitempty"`
-	SystemPrompt        string          `json:"system_prompt,omitempty"`
-	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
-	ReasoningEffort     string          `json:"reasoning_effort,omitempty"`
-	Temperature         float32         `json:"temperature,omitempty"`
-	Tools               map[string]Tool `json:"tools,omitempty"`
-	MaxToolSteps        int             `json:"max_tool_steps,omitempty"`
+	Model               string            `json:"model,omitempty"`
+	SystemPrompt        string            `json:"system_prompt,omitempty"`
+	SystemCacheControl  *CacheControl     `json:"system_cache_control,omitempty"`
+	MaxCompletionTokens int               `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string            `json:"reasoning_effort,omitempty"`
+	ThinkingBudget      int               `json:"thinking_budget,omitempty"`
+	Temperature         float32           `json:"temperature,omitempty"`
+	Tools               map[string]Tool   `json:"tools,omitempty"`
+	MaxToolSteps        int               `json:"max_tool_steps,omitempty"`
+	History             HistoryStrategy   `json:"-"`
+	Budget              *Budget           `json:"-"`
+	OnStreamEvent       func(StreamEvent) `json:"-"`
+	IncludeReasoning    bool              `json:"include_reasoning,omitempty"`
+	Search              *SearchOptions    `json:"search,omitempty"`
 }
@@ -0,0 +1,194 @@
+// token prices and cost estimation
+
+package sdk
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prices in USD per million tokens
+// CachedInput and CacheWrite default to Input, Reasoning to Output when zero
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	Output      float64 `json:"output"`
+	CachedInput float64 `json:"cached_input,omitempty"`
+	CacheWrite  float64 `json:"cache_write,omitempty"`
+	Reasoning   float64 `json:"reasoning,omitempty"`
+}
+
+// cost in USD of the given usage
+func (p ModelPrice) Cost(u Usage) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	writePrice := p.CacheWrite
+	if writePrice == 0 {
+		writePrice = p.Input
+	}
+	reasoningPrice := p.Reasoning
+	if reasoningPrice == 0 {
+		reasoningPrice = p.Output
+	}
+
+	cost := float64(u.InputTokens-u.CachedInputTokens-u.CacheCreationTokens)*p.Input +
+		float64(u.CachedInputTokens)*cachedPrice +
+		float64(u.CacheCreationTokens)*writePrice +
+		float64(u.OutputTokens-u.ReasoningTokens)*p.Output +
+		float64(u.ReasoningTokens)*reasoningPrice
+	return cost / 1_000_000
+}
+
+// maps provider and model names to prices, safe for concurrent use
+// models also match their snapshots, e.g. "gpt-4o" prices "gpt-4o-2024-08-06" and "gpt-4o-latest" but not "gpt-4o-mini"
+type PriceTable struct {
+	mu     sync.RWMutex
+	prices map[string]map[string]ModelPrice // provider -> model -> price
+}
+
+//go:embed pricing.json
+var defaultPricing []byte
+
+// table used when no other one is configured, loaded from the embedded pricing.json
+var DefaultPrices = mustLoadDefaultPrices()
+
+func mustLoadDefaultPrices() *PriceTable {
+	t := NewPriceTable()
+	if err := json.Unmarshal(defaultPricing, &t.prices); err != nil {
+		panic(fmt.Sprintf("sdk: invalid embedded pricing table: %v", err))
+	}
+	return t
+}
+
+func NewPriceTable() *PriceTable {
+	return &PriceTable{prices: map[string]map[string]ModelPrice{}}
+}
+
+func (t *PriceTable) Set(provider, model string, price ModelPrice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.prices[provider] == nil {
+		t.prices[provider] = map[string]ModelPrice{}
+	}
+	t.prices[provider][model] = price
+}
+
+// merges prices in the pricing.json format ({"provider": {"model": {...}}}), overriding existing entries
+func (t *PriceTable) Load(r io.Reader) error {
+	var prices map[string]map[string]ModelPrice
+	if err := json.NewDecoder(r).Decode(&prices); err != nil {
+		return fmt.Errorf("decoding price table: %w", err)
+	}
+	for provider, models := range prices {
+		for model, price := range models {
+			t.Set(provider, model, price)
+		}
+	}
+	return nil
+}
+
+func (t *PriceTable) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return t.Load(f)
+}
+
+// finds the price of a model, searching every provider when the given one has no match
+// (e.g. behind a fallback chain whose backends have custom names)
+func (t *PriceTable) Lookup(provider, model string) (ModelPrice, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if price, ok := lookupModel(t.prices[provider], model); ok {
+		return price, true
+	}
+	for _, name := range slices.Sorted(maps.Keys(t.prices)) {
+		if price, ok := lookupModel(t.prices[name], model); ok {
+			return price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// cost in USD, false when the model has no price
+func (t *PriceTable) Cost(provider, model string, u Usage) (float64, bool) {
+	price, ok := t.Lookup(provider, model)
+	if !ok {
+		return 0, false
+	}
+	return price.Cost(u), true
+}
+
+// exact match first, then a model name followed by a snapshot suffix
+// other suffixes such as -pro or -fast name different models and don't match
+func lookupModel[T any](models map[string]T, model string) (T, bool) {
+	if v, ok := models[model]; ok {
+		return v, true
+	}
+	for name, v := range models {
+		if suffix, ok := strings.CutPrefix(model, name+"-"); ok && isSnapshot(suffix) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// reports whether suffix is a date (2024-08-06 or 20241022) or "latest"
+func isSnapshot(suffix string) bool {
+	if suffix == "latest" {
+		return true
+	}
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if len(suffix) == len(layout) {
+			if _, err := time.Parse(layout, suffix); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uses the given table instead of DefaultPrices for Response.Cost
+func WithPricing(table *PriceTable) SDKOption {
+	return func(s *SDK) {
+		s.pricing = table
+	}
+}
+
+// cost of a completion, priced by the provider that served it when reported
+func (sdk *SDK) completionCost(resp *CompletionResponse, opts *Options) float64 {
+	table := sdk.pricing
+	if table == nil {
+		table = DefaultPrices
+	}
+
+	cost, _ := table.Cost(sdk.responseProvider(resp), responseModel(resp, opts), resp.Usage)
+	return cost
+}
+
+func (sdk *SDK) responseProvider(resp *CompletionResponse) string {
+	if resp.Provider != "" {
+		return resp.Provider
+	}
+	return ProviderName(sdk.base)
+}
+
+func responseModel(resp *CompletionResponse, opts *Options) string {
+	if resp.Model == "" && opts != nil {
+		return opts.Model
+	}
+	return resp.Model
+}
@@ -0,0 +1,67 @@
+package sdk_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+func TestPriceLookup(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		input           float64 // 0 when the model must have no price
+	}{
+		{"openai", "gpt-4o", 2.5},
+		{"openai", "gpt-4o-2024-08-06", 2.5},
+		{"openai", "gpt-4o-mini", 0.15},
+		{"openai", "gpt-4o-mini-2024-07-18", 0.15},
+		{"openai", "o1-pro", 150},
+		{"openai", "gpt-5-pro", 15},
+		{"anthropic", "claude-sonnet-4-5-20250929", 3},
+		{"anthropic", "claude-opus-4-5", 5},
+		{"anthropic", "claude-opus-4-1-20250805", 15},
+		{"xai", "grok-4-fast", 0.2},
+		{"xai", "grok-4-latest", 3},
+		{"mistral", "mistral-large-latest", 2},
+
+		// another provider's table is searched when the given one has no match
+		{"my-fallback", "gpt-4o", 2.5},
+
+		// suffixes naming other models are not priced as their base model
+		{"openai", "o1-mini-high", 0},
+		{"openai", "gpt-4o-audio-preview", 0},
+		{"xai", "grok-4-heavy", 0},
+		{"openai", "gpt-4o-2024-13-45", 0},
+		{"openai", "unknown", 0},
+	}
+	for _, tt := range tests {
+		price, ok := sdk.DefaultPrices.Lookup(tt.provider, tt.model)
+		if ok != (tt.input != 0) || price.Input != tt.input {
+			t.Errorf("Lookup(%q, %q) = %v, %v, want input price %v", tt.provider, tt.model, price.Input, ok, tt.input)
+		}
+	}
+}
+
+func TestModelPriceCost(t *testing.T) {
+	price := sdk.ModelPrice{Input: 3, Output: 15, CachedInput: 0.3, CacheWrite: 3.75}
+	tests := []struct {
+		name  string
+		price sdk.ModelPrice
+		usage sdk.Usage
+		want  float64
+	}{
+		{"input and output", price, sdk.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}, 18},
+		{"cached input", price, sdk.Usage{InputTokens: 1_000_000, CachedInputTokens: 500_000}, 1.5 + 0.15},
+		{"cache writes", price, sdk.Usage{InputTokens: 1_000_000, CacheCreationTokens: 1_000_000}, 3.75},
+		{"reasoning priced as output", price, sdk.Usage{OutputTokens: 1_000_000, ReasoningTokens: 400_000}, 15},
+		{"reasoning price", sdk.ModelPrice{Output: 10, Reasoning: 20}, sdk.Usage{OutputTokens: 1_000_000, ReasoningTokens: 500_000}, 5 + 10},
+		{"cache prices default to input", sdk.ModelPrice{Input: 2}, sdk.Usage{InputTokens: 1_000_000, CachedInputTokens: 250_000, CacheCreationTokens: 250_000}, 2},
+		{"no usage", price, sdk.Usage{}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.price.Cost(tt.usage); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: cost = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 )
 
 type Provider interface {
@@ -14,20 +15,69 @@ type Provider interface {
 	CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error)
 }
 
+// implemented by providers wrapping another one, such as CacheProvider or KeyPoolProvider
+// optional capabilities such as Embedder or TokenCounter are looked up through Unwrap
+type Wrapper interface {
+	Unwrap() Provider
+}
+
+// returns the first provider of the wrapping chain implementing T
+func capability[T any](p Provider) (T, bool) {
+	for p != nil {
+		if c, ok := p.(T); ok {
+			return c, true
+		}
+		w, ok := p.(Wrapper)
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
 type SDK struct {
-	provider Provider
+	provider    Provider // wrapped in middlewares, used for completions
+	base        Provider // without middlewares, optional capabilities such as TokenCounter are looked up through its Unwrap chain
+	middlewares []Middleware
+	observers   []Observer
+	observer    Observer
+	logger      *slog.Logger
+	logPayloads bool
+	pricing     *PriceTable
+	models      *ModelCatalog
+	validation  ValidationMode
 }
 
-func NewSDK(provider Provider) *SDK {
-	return &SDK{
+func NewSDK(provider Provider, opts ...SDKOption) *SDK {
+	s := &SDK{
 		provider: provider,
+		base:     provider,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		s.provider = s.middlewares[i](s.provider)
+	}
+	if len(s.observers) > 0 {
+		s.observer = multiObserver(s.observers)
+		s.provider = observing(s.observer, ProviderName(provider))(s.provider)
+	}
+	return s
 }
 
 type Response struct {
-	Content string
-	Stream  *Stream
-	Error   error
+	Content   string
+	Reasoning string        // reasoning of the final answer, when the provider returns it
+	Citations []Citation    // sources of the final answer, e.g. Perplexity search results
+	Images    []SearchImage // images found by the search
+	Messages  []Message     // assistant and tool turns produced by the call
+	Usage     Usage         // summed over all steps, not reported for streamed responses
+	Cost      float64       // estimated cost in USD, see PriceTable
+	Stream    *Stream
+	Error     error
 }
 
 type Stream struct {
@@ -43,16 +93,23 @@ func (s *Stream) Close() error {
 }
 
 type CompletionRequest struct {
-	Messages        []Message                                   // conversation history
-	Model           string                                      // model name
-	SystemPrompt    string                                      // initial system prompt
-	MaxTokens       int                                         // max tokens for completion
-	Temperature     float32                                     // sampling temperature
-	ReasoningEffort string                                      // e.g., "low", "medium", "high"
-	Stream          bool                                        // whether to stream the response
-	Tools           map[string]Tool                             // available tools for tool calls
-	MaxToolSteps    int                                         // for preventing infinite loop error, defaults to 5
-	OnToolCall      func(toolName string, args json.RawMessage) // for ui callbacks
+	Messages         []Message                                   // conversation history
+	Model            string                                      // model name
+	SystemPrompt     string                                      // initial system prompt
+	SystemCache      *CacheControl                               // prompt cache breakpoint after the system prompt
+	MaxTokens        int                                         // max tokens for completion
+	Temperature      float32                                     // sampling temperature
+	ReasoningEffort  string                                      // e.g., "low", "medium", "high"
+	ThinkingBudget   int                                         // reasoning tokens for providers with explicit budgets (Anthropic)
+	Stream           bool                                        // whether to stream the response
+	Tools            map[string]Tool                             // available tools for tool calls
+	MaxToolSteps     int                                         // for preventing infinite loop error, defaults to 5
+	OnToolCall       func(toolName string, args json.RawMessage) // for ui callbacks
+	OnStreamEvent    func(event StreamEvent)                     // typed stream events such as reasoning deltas
+	IncludeReasoning bool                                        // also writes reasoning to the stream, inside <think> tags
+	Search           *SearchOptions                              // web search filters for providers with built-in search (Perplexity)
+	History          HistoryStrategy                             // trims or summarizes history before each call
+	Budget           *Budget                                     // token, cost and time limits for the whole tool loop
 }
 
 func (sdk *SDK) ChatCompletion(ctx context.Context, req *CompletionRequest) *Response {
@@ -61,44 +118,113 @@ func (sdk *SDK) ChatCompletion(ctx context.Context, req *CompletionRequest) *Res
 		req.MaxToolSteps = 5
 	}
 
-	// setting up options
-	opts := &Options{
-		Model:               req.Model,
-		SystemPrompt:        req.SystemPrompt,
-		MaxCompletionTokens: req.MaxTokens,
-		Temperature:         req.Temperature,
-		ReasoningEffort:     req.ReasoningEffort,
-		Tools:               req.Tools,
-		MaxToolSteps:        req.MaxToolSteps,
-	}
+	opts := req.options()
 
 	hasTools := len(opts.Tools) > 0
 
+	ctx = withModerationMemo(sdk.withLogger(ctx))
+	logger := Logger(ctx)
+	logger.Debug("chat completion",
+		"provider", ProviderName(sdk.base),
+		"model", req.Model,
+		"stream", req.Stream,
+		"messages", len(req.Messages),
+		"tools", len(req.Tools),
+	)
+
+	if err := sdk.validate(ctx, req.Messages, opts); err != nil {
+		return &Response{Error: err}
+	}
+
+	stop := func() {}
+	if req.Budget != nil {
+		if err := req.Budget.check(); err != nil {
+			logger.Warn("budget exhausted", "limit", err.Limit)
+			return &Response{Error: err}
+		}
+		ctx, stop = req.Budget.start(ctx)
+	}
+
+	var resp *Response
 	switch {
 	case req.Stream && hasTools:
-		return sdk.streamingCompletionWithTools(ctx, req.Messages, opts, req.Tools, req.OnToolCall)
+		resp = sdk.streamingCompletionWithTools(ctx, req.Messages, opts, req.Tools, req.OnToolCall)
 	case req.Stream:
-		return sdk.streamingCompletion(ctx, req.Messages, opts)
+		resp = sdk.streamingCompletion(ctx, req.Messages, opts)
 
 	case hasTools:
-		return sdk.chatCompletionWithTools(ctx, req.Messages, opts, req.Tools, req.OnToolCall)
+		resp = sdk.chatCompletionWithTools(ctx, req.Messages, opts, req.Tools, req.OnToolCall)
 
 	default:
-		return sdk.simpleCompletion(ctx, req.Messages, opts)
+		resp = sdk.simpleCompletion(ctx, req.Messages, opts)
+	}
+
+	if resp.Stream != nil {
+		resp.Stream.reader = &budgetStream{ReadCloser: resp.Stream.reader, stop: stop}
+	} else {
+		stop()
+	}
+
+	if resp.Error != nil {
+		logger.Error("chat completion failed", "provider", ProviderName(sdk.base), "model", req.Model, "error", resp.Error)
+	}
+	return resp
+}
+
+// setting up options
+func (req *CompletionRequest) options() *Options {
+	return &Options{
+		Model:               req.Model,
+		SystemPrompt:        req.SystemPrompt,
+		SystemCacheControl:  req.SystemCache,
+		MaxCompletionTokens: req.MaxTokens,
+		Temperature:         req.Temperature,
+		ReasoningEffort:     req.ReasoningEffort,
+		ThinkingBudget:      req.ThinkingBudget,
+		OnStreamEvent:       req.OnStreamEvent,
+		IncludeReasoning:    req.IncludeReasoning,
+		Search:              req.Search,
+		Tools:               req.Tools,
+		MaxToolSteps:        req.MaxToolSteps,
+		History:             req.History,
+		Budget:              req.Budget,
 	}
 }
 
 func (sdk *SDK) simpleCompletion(ctx context.Context, messages []Message, opts *Options) *Response {
+	messages, err := sdk.applyHistory(ctx, messages, opts)
+	if err != nil {
+		return &Response{Error: err}
+	}
+
 	compResp, err := sdk.provider.CreateCompletion(ctx, messages, opts)
-	return &Response{Content: compResp.Content, Error: err}
+	if err != nil {
+		return &Response{Error: withTranscript(opts.Budget.wrap(ctx, err), nil)}
+	}
+	sdk.chargeBudget(opts.Budget, compResp, opts)
+	return &Response{
+		Content:   compResp.Content,
+		Reasoning: compResp.Reasoning,
+		Citations: compResp.Citations,
+		Images:    compResp.Images,
+		Messages:  []Message{assistantMessage(compResp)},
+		Usage:     compResp.Usage,
+		Cost:      sdk.completionCost(compResp, opts),
+	}
 }
 
 func (sdk *SDK) streamingCompletion(ctx context.Context, messages []Message, opts *Options) *Response {
-	stream, err := sdk.provider.CreateCompletionStream(ctx, messages, opts)
+	messages, err := sdk.applyHistory(ctx, messages, opts)
 	if err != nil {
 		return &Response{Error: err}
 	}
-	return &Response{Stream: &Stream{reader: stream}}
+
+	opts, charge := sdk.chargeStream(opts)
+	stream, err := sdk.provider.CreateCompletionStream(ctx, messages, opts)
+	if err != nil {
+		return &Response{Error: withTranscript(opts.Budget.wrap(ctx, err), nil)}
+	}
+	return &Response{Stream: &Stream{reader: &observedStream{ReadCloser: stream, onEnd: func(int, error) { charge() }}}}
 }
 
 func (sdk *SDK) chatCompletionWithTools(
@@ -109,62 +235,52 @@ func (sdk *SDK) chatCompletionWithTools(
 	onToolCall func(string, json.RawMessage),
 ) *Response {
 	messages := append([]Message{}, initialMessages...)
+	var usage Usage
+	var cost float64
 
 	for step := 0; step < opts.MaxToolSteps; step++ {
-		compResp, err := sdk.provider.CreateCompletion(ctx, messages, opts)
+		history, err := sdk.applyHistory(ctx, messages, opts)
+		if err != nil {
+			return &Response{Messages: messages[len(initialMessages):], Usage: usage, Cost: cost, Error: err}
+		}
+
+		compResp, err := sdk.provider.CreateCompletion(ctx, history, opts)
 
 		if err != nil {
-			return &Response{Error: err}
+			transcript := messages[len(initialMessages):]
+			err = withTranscript(opts.Budget.wrap(ctx, err), transcript)
+			return &Response{Messages: transcript, Usage: usage, Cost: cost, Error: err}
 		}
 
+		usage = usage.Add(compResp.Usage)
+		cost += sdk.completionCost(compResp, opts)
+		exceeded := sdk.chargeBudget(opts.Budget, compResp, opts)
+
 		if len(compResp.ToolCalls) == 0 {
-			return &Response{Content: compResp.Content}
+			messages = append(messages, assistantMessage(compResp))
+			return &Response{Content: compResp.Content, Reasoning: compResp.Reasoning, Citations: compResp.Citations, Images: compResp.Images, Messages: messages[len(initialMessages):], Usage: usage, Cost: cost}
 		}
 
-		messages = append(messages, Message{
-			Role:      "assistant",
-			Content:   compResp.Content,
-			ToolCalls: compResp.ToolCalls,
-		})
-
-		for _, toolCall := range compResp.ToolCalls {
-			tool, exists := tools[toolCall.Name]
-			if !exists {
-				messages = append(messages, Message{
-					Role:       "tool",
-					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf(`{"error": "Tool" '%s' not found"}`, toolCall.Name),
-				})
-				continue
-			}
+		// stops before running the tools, the transcript ends with the last completed step
+		if exceeded != nil {
+			transcript := messages[len(initialMessages):]
+			Logger(ctx).Warn("budget exceeded", "limit", exceeded.Limit, "step", step+1)
+			return &Response{Messages: transcript, Usage: usage, Cost: cost, Error: withTranscript(exceeded, transcript)}
+		}
 
-			if onToolCall != nil {
-				onToolCall(toolCall.Name, toolCall.Arguments)
-			}
+		Logger(ctx).Debug("tool step", "step", step+1, "tool_calls", len(compResp.ToolCalls))
 
-			result, err := tool.Execute(ctx, toolCall.Arguments)
+		messages = append(messages, assistantMessage(compResp))
 
-			var resultContent string
-			if err != nil {
-				resultContent = fmt.Sprintf(`{"error": "%s"}`, err.Error())
-			} else {
-				resultBytes, marshalErr := json.Marshal(result)
-				if marshalErr != nil {
-					resultContent = fmt.Sprintf(`{"error": "Failes to marshal toolCall result: %s"}`, marshalErr.Error())
-				} else {
-					resultContent = string(resultBytes)
-				}
-			}
-
-			messages = append(messages, Message{
-				Role:       "tool",
-				ToolCallID: toolCall.ID,
-				Content:    resultContent,
-			})
+		for _, toolCall := range compResp.ToolCalls {
+			messages = append(messages, sdk.executeToolCall(ctx, tools, toolCall, onToolCall))
 		}
 	}
 	return &Response{
-		Error: fmt.Errorf("reached maximum tool steps (%d) without final answer", opts.MaxToolSteps),
+		Messages: messages[len(initialMessages):],
+		Usage:    usage,
+		Cost:     cost,
+		Error:    fmt.Errorf("reached maximum tool steps (%d) without final answer", opts.MaxToolSteps),
 	}
 }
 
@@ -183,70 +299,124 @@ func (sdk *SDK) streamingCompletionWithTools(
 		defer w.Close()
 
 		for step := 0; step < opts.MaxToolSteps; step++ {
-			compResp, err := sdk.provider.CreateCompletion(ctx, messages, opts)
+			history, err := sdk.applyHistory(ctx, messages, opts)
 			if err != nil {
 				w.CloseWithError(err)
 				return
 			}
 
+			compResp, err := sdk.provider.CreateCompletion(ctx, history, opts)
+			if err != nil {
+				w.CloseWithError(withTranscript(opts.Budget.wrap(ctx, err), messages[len(initialMessages):]))
+				return
+			}
+
+			if exceeded := sdk.chargeBudget(opts.Budget, compResp, opts); exceeded != nil {
+				Logger(ctx).Warn("budget exceeded", "limit", exceeded.Limit, "step", step+1)
+				if len(compResp.ToolCalls) > 0 {
+					w.CloseWithError(withTranscript(exceeded, messages[len(initialMessages):]))
+					return
+				}
+				// the answer is already paid for, it is written instead of streaming it again
+				w.Write([]byte(compResp.Content))
+				return
+			}
+
 			if len(compResp.ToolCalls) > 0 {
 				if compResp.Content != "" {
 					w.Write([]byte(compResp.Content))
 				}
 
-				messages = append(messages, Message{
-					Role:      "assistant",
-					Content:   compResp.Content,
-					ToolCalls: compResp.ToolCalls,
-				})
+				Logger(ctx).Debug("tool step", "step", step+1, "tool_calls", len(compResp.ToolCalls))
+
+				messages = append(messages, assistantMessage(compResp))
 
 				for _, toolCall := range compResp.ToolCalls {
-					tool, exists := tools[toolCall.Name]
-					if !exists {
-						messages = append(messages, Message{
-							Role:       "tool",
-							ToolCallID: toolCall.ID,
-							Content:    fmt.Sprintf(`{"error": "Tool" '%s' not found"}`, toolCall.Name),
-						})
-						continue
-					}
-
-					if onToolCall != nil {
-						onToolCall(toolCall.Name, toolCall.Arguments)
-					}
-
-					result, err := tool.Execute(ctx, toolCall.Arguments)
-
-					var resultContent string
-					if err != nil {
-						resultContent = fmt.Sprintf(`{"error": "%s"}`, err.Error())
-					} else {
-						resultBytes, marshalErr := json.Marshal(result)
-						if marshalErr != nil {
-							resultContent = fmt.Sprintf(`{"error": "Failes to marshal toolCall result: %s"}`, marshalErr.Error())
-						} else {
-							resultContent = string(resultBytes)
-						}
-					}
-
-					messages = append(messages, Message{
-						Role:       "tool",
-						ToolCallID: toolCall.ID,
-						Content:    resultContent,
-					})
+					messages = append(messages, sdk.executeToolCall(ctx, tools, toolCall, onToolCall))
 				}
 			}
 			// no tool calls - stream the response
-			stream, err := sdk.provider.CreateCompletionStream(ctx, messages, opts)
+			history, err = sdk.applyHistory(ctx, messages, opts)
 			if err != nil {
 				w.CloseWithError(err)
 				return
 			}
+
+			streamOpts, charge := sdk.chargeStream(opts)
+			stream, err := sdk.provider.CreateCompletionStream(ctx, history, streamOpts)
+			if err != nil {
+				w.CloseWithError(withTranscript(opts.Budget.wrap(ctx, err), messages[len(initialMessages):]))
+				return
+			}
 			io.Copy(w, stream)
 			stream.Close()
+			charge()
 			return
 		}
 		w.CloseWithError(fmt.Errorf("max tool calls reached"))
 	}()
 	return &Response{Stream: &Stream{reader: r}}
 }
+
+// assistant turn of a completion, keeping the reasoning blocks that must be sent back with tool results
+func assistantMessage(resp *CompletionResponse) Message {
+	return Message{
+		Role:            "assistant",
+		Content:         resp.Content,
+		ToolCalls:       resp.ToolCalls,
+		Reasoning:       resp.Reasoning,
+		ReasoningBlocks: resp.ReasoningBlocks,
+	}
+}
+
+// runs a tool call and returns the tool message with its result or error
+func (sdk *SDK) executeToolCall(
+	ctx context.Context,
+	tools map[string]Tool,
+	toolCall ToolCallRequest,
+	onToolCall func(string, json.RawMessage),
+) Message {
+	logger := Logger(ctx)
+
+	tool, exists := tools[toolCall.Name]
+	if !exists {
+		logger.Warn("tool not found", "tool", toolCall.Name, "tool_call_id", toolCall.ID)
+		return Message{
+			Role:       "tool",
+			ToolCallID: toolCall.ID,
+			Content:    fmt.Sprintf(`{"error": "Tool" '%s' not found"}`, toolCall.Name),
+		}
+	}
+
+	if onToolCall != nil {
+		onToolCall(toolCall.Name, toolCall.Arguments)
+	}
+
+	logger.Debug("executing tool", "tool", toolCall.Name, "tool_call_id", toolCall.ID)
+
+	var result any
+	var err error
+	sdk.observeTool(ctx, toolCall, func(ctx context.Context) error {
+		result, err = tool.Execute(ctx, toolCall.Arguments)
+		return err
+	})
+
+	var resultContent string
+	if err != nil {
+		logger.Warn("tool failed", "tool", toolCall.Name, "tool_call_id", toolCall.ID, "error", err)
+		resultContent = fmt.Sprintf(`{"error": "%s"}`, err.Error())
+	} else {
+		resultBytes, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			resultContent = fmt.Sprintf(`{"error": "Failes to marshal toolCall result: %s"}`, marshalErr.Error())
+		} else {
+			resultContent = string(resultBytes)
+		}
+	}
+
+	return Message{
+		Role:       "tool",
+		ToolCallID: toolCall.ID,
+		Content:    resultContent,
+	}
+}
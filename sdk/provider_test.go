@@ -0,0 +1,68 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// provider with a token counting endpoint, reporting the key it was built with
+type countingProvider struct {
+	sdk.Provider
+	key string
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) CountTokens(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (int, error) {
+	if p.key == "key-b" {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+func TestCapabilitiesThroughWrappers(t *testing.T) {
+	base, _ := newScripted(func(n int, messages []sdk.Message, stream bool) string { return textReply("ok", 1, 1) })
+	pool := sdk.NewKeyPoolProvider(func(key sdk.PoolKey) sdk.Provider {
+		return &countingProvider{Provider: base, key: key.APIKey}
+	}, sdk.RoundRobin, sdk.PoolKey{APIKey: "key-a"}, sdk.PoolKey{APIKey: "key-b"})
+
+	wrapped := sdk.NewCacheProvider(
+		sdk.NewRateLimitProvider(
+			sdk.NewFallbackProvider(sdk.FallbackBackend{Name: "pool", Provider: pool}),
+			sdk.RateLimit{},
+		),
+		sdk.NewMemoryCache(10), 0,
+	)
+	client := sdk.NewSDK(wrapped)
+	req := &sdk.CompletionRequest{Messages: []sdk.Message{{Role: "user", Content: "hi"}}}
+
+	// the key pool spreads capability calls over its keys
+	var counts []int
+	for range 2 {
+		n, err := client.CountTokens(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, n)
+	}
+	if counts[0] == counts[1] {
+		t.Fatalf("counts = %v, want one call per key", counts)
+	}
+	if name := sdk.ProviderName(wrapped); name != "counting" {
+		t.Fatalf("ProviderName = %q", name)
+	}
+
+	// a capability missing along the whole chain is still unsupported
+	if _, err := client.Embed(context.Background(), &sdk.EmbedRequest{Input: []string{"hi"}}); !errors.Is(err, sdk.ErrUnsupported) {
+		t.Fatalf("Embed err = %v, want ErrUnsupported", err)
+	}
+
+	// an empty pool has no provider to unwrap to
+	pool.SetKeys()
+	if _, err := client.CountTokens(context.Background(), req); !errors.Is(err, sdk.ErrUnsupported) {
+		t.Fatalf("CountTokens err = %v, want ErrUnsupported", err)
+	}
+}
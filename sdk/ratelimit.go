@@ -0,0 +1,261 @@
+// client-side rate limiting by requests and tokens per minute
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	RequestsPerMinute int // 0 only follows the provider's rate limit headers
+	TokensPerMinute   int // 0 only follows the provider's rate limit headers, counts estimated prompt and completion tokens
+}
+
+// waits for request and token budget before calling the wrapped provider
+// limits adapt to x-ratelimit-remaining-* (and anthropic-ratelimit-*) response headers
+type RateLimitProvider struct {
+	Provider Provider
+	Default  RateLimit
+	Models   map[string]RateLimit // per model limits, override Default
+
+	// estimates tokens used by a request, defaults to EstimateTokens plus MaxCompletionTokens
+	Estimate func(messages []Message, opts *Options) int
+
+	mu      sync.Mutex
+	buckets map[string]*rateBuckets
+}
+
+type rateBuckets struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+func NewRateLimitProvider(provider Provider, limit RateLimit) *RateLimitProvider {
+	return &RateLimitProvider{
+		Provider: provider,
+		Default:  limit,
+	}
+}
+
+// capability calls such as Embed go around the limits
+func (r *RateLimitProvider) Unwrap() Provider {
+	return r.Provider
+}
+
+func (r *RateLimitProvider) CreateCompletion(ctx context.Context, messages []Message, opts *Options) (*CompletionResponse, error) {
+	b := r.bucketsFor(opts)
+	if err := r.wait(ctx, b, messages, opts); err != nil {
+		return nil, err
+	}
+	return r.Provider.CreateCompletion(WithHeaderObserver(ctx, b.adapt), messages, opts)
+}
+
+func (r *RateLimitProvider) CreateCompletionStream(ctx context.Context, messages []Message, opts *Options) (io.ReadCloser, error) {
+	b := r.bucketsFor(opts)
+	if err := r.wait(ctx, b, messages, opts); err != nil {
+		return nil, err
+	}
+	return r.Provider.CreateCompletionStream(WithHeaderObserver(ctx, b.adapt), messages, opts)
+}
+
+// the request slot is given back when ctx is done while waiting for tokens
+func (r *RateLimitProvider) wait(ctx context.Context, b *rateBuckets, messages []Message, opts *Options) error {
+	if err := b.requests.wait(ctx, 1); err != nil {
+		return err
+	}
+
+	estimate := r.Estimate
+	if estimate == nil {
+		estimate = func(messages []Message, opts *Options) int {
+			n := EstimateTokens(messages)
+			if opts != nil {
+				n += opts.MaxCompletionTokens
+			}
+			return n
+		}
+	}
+	if err := b.tokens.wait(ctx, float64(estimate(messages, opts))); err != nil {
+		b.requests.release(1)
+		return err
+	}
+	return nil
+}
+
+func (r *RateLimitProvider) bucketsFor(opts *Options) *rateBuckets {
+	model := ""
+	if opts != nil {
+		model = opts.Model
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, ok := r.Models[model]
+	if !ok {
+		model, limit = "", r.Default
+	}
+
+	if r.buckets == nil {
+		r.buckets = map[string]*rateBuckets{}
+	}
+	b, ok := r.buckets[model]
+	if !ok {
+		b = &rateBuckets{
+			requests: newTokenBucket(limit.RequestsPerMinute),
+			tokens:   newTokenBucket(limit.TokensPerMinute),
+		}
+		r.buckets[model] = b
+	}
+	return b
+}
+
+// lowers the local budget to what the provider reports as remaining
+func (b *rateBuckets) adapt(h http.Header) {
+	if remaining, ok := headerInt(h, "x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining"); ok {
+		reset := headerReset(h, "x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset")
+		b.requests.sync(float64(remaining), reset)
+	}
+	if remaining, ok := headerInt(h, "x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining"); ok {
+		reset := headerReset(h, "x-ratelimit-reset-tokens", "anthropic-ratelimit-tokens-reset")
+		b.tokens.sync(float64(remaining), reset)
+	}
+}
+
+func headerInt(h http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parses reset headers, either durations ("6m0s", "1.5s", "20ms") or RFC 3339 timestamps
+func headerReset(h http.Header, names ...string) time.Duration {
+	for _, name := range names {
+		v := strings.TrimSpace(h.Get(name))
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return time.Until(t)
+		}
+	}
+	return 0
+}
+
+// token bucket refilled continuously at perMinute/60 per second
+// without a limit it only blocks while the provider reports an exhausted budget
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // per second
+	last     time.Time
+	blocked  time.Time // provider reported an exhausted budget until then
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return &tokenBucket{}
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// blocks until n tokens are available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	for {
+		delay := b.reserve(n)
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// takes n tokens if possible, otherwise returns how long to wait
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.blocked) {
+		return b.blocked.Sub(now)
+	}
+	if b.rate == 0 {
+		return 0
+	}
+
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// requests larger than the bucket only need a full bucket
+	n = min(n, b.capacity)
+	if b.tokens >= n {
+		b.tokens -= n
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// gives back n tokens taken by reserve
+func (b *tokenBucket) release(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.capacity, b.tokens+n)
+}
+
+func (b *tokenBucket) sync(remaining float64, reset time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remaining < b.tokens {
+		b.tokens = remaining
+	}
+	if remaining <= 0 && reset > 0 {
+		b.blocked = time.Now().Add(reset)
+	}
+}
+
+type headerObserverKey struct{}
+
+// registers fn to receive the headers of provider responses made with the returned context
+func WithHeaderObserver(ctx context.Context, fn func(http.Header)) context.Context {
+	if parent, ok := ctx.Value(headerObserverKey{}).(func(http.Header)); ok {
+		next := fn
+		fn = func(h http.Header) {
+			parent(h)
+			next(h)
+		}
+	}
+	return context.WithValue(ctx, headerObserverKey{}, fn)
+}
+
+// called by providers with the headers of every response
+func ObserveHeaders(ctx context.Context, h http.Header) {
+	if fn, ok := ctx.Value(headerObserverKey{}).(func(http.Header)); ok {
+		fn(h)
+	}
+}
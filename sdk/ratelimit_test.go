@@ -0,0 +1,84 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// provider reporting header on every completion
+func headerProvider(header http.Header) sdk.Provider {
+	return &sdk.ProviderFuncs{
+		Completion: func(ctx context.Context, messages []sdk.Message, opts *sdk.Options) (*sdk.CompletionResponse, error) {
+			sdk.ObserveHeaders(ctx, header)
+			return &sdk.CompletionResponse{Content: "ok"}, nil
+		},
+	}
+}
+
+func completeWithin(r *sdk.RateLimitProvider, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	_, err := r.CreateCompletion(ctx, []sdk.Message{{Role: "user", Content: "hi"}}, &sdk.Options{})
+	return err
+}
+
+func TestRateLimitRequestsPerMinute(t *testing.T) {
+	r := sdk.NewRateLimitProvider(headerProvider(http.Header{}), sdk.RateLimit{RequestsPerMinute: 2})
+
+	for i := range 2 {
+		if err := completeWithin(r, 50*time.Millisecond); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if err := completeWithin(r, 50*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third request err = %v, want it to wait for the next minute", err)
+	}
+}
+
+func TestRateLimitAdaptsWithoutLimits(t *testing.T) {
+	r := sdk.NewRateLimitProvider(headerProvider(http.Header{
+		"X-Ratelimit-Remaining-Requests": {"0"},
+		"X-Ratelimit-Reset-Requests":     {"300ms"},
+	}), sdk.RateLimit{})
+
+	if err := completeWithin(r, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// the provider reported an exhausted budget, the next call waits for the reset
+	if err := completeWithin(r, 50*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the call to wait for the reset", err)
+	}
+	start := time.Now()
+	if err := completeWithin(r, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Fatalf("waited %v, want about 250ms", waited)
+	}
+}
+
+func TestRateLimitKeepsRequestSlotOnCancel(t *testing.T) {
+	estimate := 60
+	r := sdk.NewRateLimitProvider(headerProvider(http.Header{}), sdk.RateLimit{RequestsPerMinute: 2, TokensPerMinute: 100})
+	r.Estimate = func(messages []sdk.Message, opts *sdk.Options) int { return estimate }
+
+	if err := completeWithin(r, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// 40 tokens left, the request is cancelled while waiting for 60
+	if err := completeWithin(r, 50*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the call to wait for tokens", err)
+	}
+
+	// the cancelled request gave its slot back
+	estimate = 0
+	if err := completeWithin(r, 50*time.Millisecond); err != nil {
+		t.Fatalf("err = %v, want the second request slot to be available", err)
+	}
+}
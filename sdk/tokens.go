@@ -0,0 +1,20 @@
+// provider-native token counting
+
+package sdk
+
+import "context"
+
+// implemented by providers that can count prompt tokens server side
+type TokenCounter interface {
+	CountTokens(ctx context.Context, messages []Message, opts *Options) (int, error)
+}
+
+// counts the prompt tokens of a request with the provider's counting endpoint
+// returns ErrUnsupported when the provider has no such endpoint
+func (sdk *SDK) CountTokens(ctx context.Context, req *CompletionRequest) (int, error) {
+	counter, ok := capability[TokenCounter](sdk.base)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return counter.CountTokens(ctx, req.Messages, req.options())
+}
@@ -9,9 +9,10 @@ import (
 )
 
 type Tool struct {
-	Description string          `json:"description,omitempty"`
-	InputSchema InputSchema     `json:"inputSchema,omitempty"`
-	Execute     ToolExecuteFunc `json:"-,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  InputSchema     `json:"inputSchema,omitempty"`
+	Execute      ToolExecuteFunc `json:"-,omitempty"`
+	CacheControl *CacheControl   `json:"-"` // prompt cache breakpoint after this tool definition
 }
 
 type InputSchema map[string]Property
@@ -0,0 +1,181 @@
+// byte pair encoding for the cl100k_base and o200k_base encodings
+
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// tiktoken rank files (<name>.tiktoken) shipped with the package
+//
+//go:embed encodings
+var embedded embed.FS
+
+type Encoding struct {
+	Name  string
+	ranks map[string]int
+	split func(string) []string
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+	failed      = map[string]error{} // embedded files that could not be loaded, not retried
+)
+
+var splitters = map[string]func(string) []string{
+	"cl100k_base": splitCl100k,
+	"o200k_base":  splitO200k,
+}
+
+// registers a tiktoken rank file for an encoding, replacing the embedded one
+func RegisterEncoding(name string, r io.Reader) error {
+	split, ok := splitters[name]
+	if !ok {
+		return fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	ranks, err := parseRanks(r)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", name, err)
+	}
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[name] = &Encoding{Name: name, ranks: ranks, split: split}
+	delete(failed, name)
+	return nil
+}
+
+// returns a registered or embedded encoding
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	if err, ok := failed[name]; ok {
+		return nil, err
+	}
+
+	split, ok := splitters[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	f, err := embedded.Open("encodings/" + name + ".tiktoken")
+	if err != nil {
+		failed[name] = fmt.Errorf("encoding %s is not available: %w", name, err)
+		return nil, failed[name]
+	}
+	defer f.Close()
+
+	ranks, err := parseRanks(f)
+	if err != nil {
+		failed[name] = fmt.Errorf("loading %s: %w", name, err)
+		return nil, failed[name]
+	}
+
+	enc := &Encoding{Name: name, ranks: ranks, split: split}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// parses the tiktoken format: one "<base64 token> <rank>" pair per line
+func parseRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int, 200000)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("empty rank file")
+	}
+	return ranks, nil
+}
+
+// encodes text into token ids
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairEncode([]byte(piece))...)
+	}
+	return tokens
+}
+
+// number of tokens in text
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(e.bytePairEncode([]byte(piece)))
+	}
+	return count
+}
+
+// merges the lowest ranked adjacent pair until no pair is in the vocabulary
+func (e *Encoding) bytePairEncode(piece []byte) []int {
+	if len(piece) == 1 {
+		return []int{e.ranks[string(piece)]}
+	}
+
+	// boundaries of the current parts
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		minRank, minIdx := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < minRank {
+				minRank, minIdx = rank, i
+			}
+		}
+		if minIdx < 0 {
+			break
+		}
+		bounds = append(bounds[:minIdx+1], bounds[minIdx+2:]...)
+	}
+
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		tokens = append(tokens, e.ranks[string(piece[bounds[i]:bounds[i+1]])])
+	}
+	return tokens
+}
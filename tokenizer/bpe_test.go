@@ -0,0 +1,37 @@
+package tokenizer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEncodeKnownTokens(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []int
+	}{
+		{"cl100k_base", "hello world", []int{15339, 1917}},
+		{"cl100k_base", "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+		{"o200k_base", "hello world", []int{24912, 2375}},
+	}
+
+	for _, tt := range tests {
+		enc, err := GetEncoding(tt.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := enc.Encode(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Encode(%q) = %v, want %v", tt.encoding, tt.text, got, tt.want)
+		}
+		if got := enc.Count(tt.text); got != len(tt.want) {
+			t.Errorf("%s: Count(%q) = %d, want %d", tt.encoding, tt.text, got, len(tt.want))
+		}
+	}
+}
+
+func TestCountTextUsesBPE(t *testing.T) {
+	if got := CountText("tiktoken is great!", "gpt-4"); got != 6 {
+		t.Errorf("CountText = %d, want 6", got)
+	}
+}
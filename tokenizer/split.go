@@ -0,0 +1,182 @@
+// pre-tokenization, hand written versions of the tiktoken split patterns
+// (Go regexp has no lookahead, which the patterns rely on)
+
+package tokenizer
+
+import (
+	"unicode"
+)
+
+func isLetter(r rune) bool { return unicode.IsLetter(r) }
+func isNumber(r rune) bool { return unicode.IsNumber(r) }
+func isSpace(r rune) bool  { return unicode.IsSpace(r) }
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+// [^\r\n\p{L}\p{N}]
+func isPrefix(r rune) bool {
+	return !isNewline(r) && !isLetter(r) && !isNumber(r)
+}
+
+// [^\s\p{L}\p{N}]
+func isSymbol(r rune) bool {
+	return !isSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpperClass(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLowerClass(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// length of a contraction ('s, 't, 're, 've, 'm, 'll, 'd) at the start of rs
+func contraction(rs []rune) int {
+	if len(rs) < 2 || rs[0] != '\'' {
+		return 0
+	}
+	switch unicode.ToLower(rs[1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if len(rs) >= 3 {
+		pair := string([]rune{unicode.ToLower(rs[1]), unicode.ToLower(rs[2])})
+		if pair == "re" || pair == "ve" || pair == "ll" {
+			return 3
+		}
+	}
+	return 0
+}
+
+// whitespace alternatives shared by both patterns:
+// \s*[\r\n]+ | \s+(?!\S) | \s+
+func matchWhitespace(rs []rune) int {
+	end := 0
+	for end < len(rs) && isSpace(rs[end]) {
+		end++
+	}
+	if end == 0 {
+		return 0
+	}
+
+	lastNewline := -1
+	for i := 0; i < end; i++ {
+		if isNewline(rs[i]) {
+			lastNewline = i
+		}
+	}
+	if lastNewline >= 0 {
+		return lastNewline + 1
+	}
+
+	if end == len(rs) || end == 1 {
+		return end
+	}
+	// leave the last space to prefix the next word
+	return end - 1
+}
+
+// digits, at most three at a time
+func matchNumber(rs []rune) int {
+	n := 0
+	for n < len(rs) && n < 3 && isNumber(rs[n]) {
+		n++
+	}
+	return n
+}
+
+// " ?[^\s\p{L}\p{N}]+" followed by trailing characters accepted by tail
+func matchSymbols(rs []rune, tail func(rune) bool) int {
+	n := 0
+	if n < len(rs) && rs[n] == ' ' {
+		n++
+	}
+	start := n
+	for n < len(rs) && isSymbol(rs[n]) {
+		n++
+	}
+	if n == start {
+		return 0
+	}
+	for n < len(rs) && tail(rs[n]) {
+		n++
+	}
+	return n
+}
+
+func splitWith(text string, next func(rs []rune) int) []string {
+	rs := []rune(text)
+	var pieces []string
+	for len(rs) > 0 {
+		n := next(rs)
+		if n <= 0 {
+			n = 1
+		}
+		pieces = append(pieces, string(rs[:n]))
+		rs = rs[n:]
+	}
+	return pieces
+}
+
+// (?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitCl100k(text string) []string {
+	return splitWith(text, func(rs []rune) int {
+		if n := contraction(rs); n > 0 {
+			return n
+		}
+
+		start := 0
+		if isPrefix(rs[0]) && len(rs) > 1 && isLetter(rs[1]) {
+			start = 1
+		}
+		n := start
+		for n < len(rs) && isLetter(rs[n]) {
+			n++
+		}
+		if n > start {
+			return n
+		}
+
+		if n := matchNumber(rs); n > 0 {
+			return n
+		}
+		if n := matchSymbols(rs, isNewline); n > 0 {
+			return n
+		}
+		return matchWhitespace(rs)
+	})
+}
+
+// [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+// |[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+// |\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitO200k(text string) []string {
+	return splitWith(text, func(rs []rune) int {
+		start := 0
+		if isPrefix(rs[0]) && len(rs) > 1 && (isUpperClass(rs[1]) || isLowerClass(rs[1])) {
+			start = 1
+		}
+		n := start
+		for n < len(rs) && isUpperClass(rs[n]) {
+			n++
+		}
+		for n < len(rs) && isLowerClass(rs[n]) {
+			n++
+		}
+		if n > start {
+			return n + contraction(rs[n:])
+		}
+
+		if n := matchNumber(rs); n > 0 {
+			return n
+		}
+		if n := matchSymbols(rs, func(r rune) bool { return isNewline(r) || r == '/' }); n > 0 {
+			return n
+		}
+		return matchWhitespace(rs)
+	})
+}
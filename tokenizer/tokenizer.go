@@ -0,0 +1,224 @@
+// offline token counting for messages and tools
+
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+type Family string
+
+const (
+	FamilyOpenAI    Family = "openai"
+	FamilyAnthropic Family = "anthropic"
+	FamilyGemini    Family = "gemini"
+	FamilyMistral   Family = "mistral"
+	FamilyLlama     Family = "llama"
+	FamilyGeneric   Family = "generic"
+)
+
+// detects the tokenizer family from a model id
+// provider prefixes such as "openai/" or "anthropic/" are handled
+func FamilyForModel(model string) Family {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(m, "claude"):
+		return FamilyAnthropic
+	case strings.HasPrefix(m, "gemini"), strings.HasPrefix(m, "gemma"):
+		return FamilyGemini
+	case strings.Contains(m, "mistral"), strings.Contains(m, "mixtral"), strings.HasPrefix(m, "codestral"),
+		strings.HasPrefix(m, "magistral"), strings.HasPrefix(m, "ministral"), strings.HasPrefix(m, "pixtral"),
+		strings.HasPrefix(m, "devstral"):
+		return FamilyMistral
+	case strings.Contains(m, "llama"), strings.HasPrefix(m, "sonar"):
+		return FamilyLlama
+	case strings.HasPrefix(m, "gpt"), strings.HasPrefix(m, "chatgpt"), strings.HasPrefix(m, "o1"),
+		strings.HasPrefix(m, "o3"), strings.HasPrefix(m, "o4"), strings.HasPrefix(m, "text-embedding"):
+		return FamilyOpenAI
+	}
+	return FamilyGeneric
+}
+
+// returns the BPE encoding name used by an OpenAI model, or "" for other families
+func EncodingForModel(model string) string {
+	if FamilyForModel(model) != FamilyOpenAI {
+		return ""
+	}
+
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(m, "gpt-4o"), strings.HasPrefix(m, "gpt-4.1"), strings.HasPrefix(m, "gpt-4.5"),
+		strings.HasPrefix(m, "gpt-5"), strings.HasPrefix(m, "chatgpt"), strings.HasPrefix(m, "o1"),
+		strings.HasPrefix(m, "o3"), strings.HasPrefix(m, "o4"), strings.HasPrefix(m, "gpt-oss"):
+		return "o200k_base"
+	}
+	return "cl100k_base"
+}
+
+// calibration of the heuristic per family, measured on English prose and code
+type calibration struct {
+	charsPerToken float64 // long latin words
+	runesPerToken float64 // non latin scripts
+	scale         float64 // overall correction
+}
+
+var calibrations = map[Family]calibration{
+	FamilyOpenAI:    {charsPerToken: 4.0, runesPerToken: 1.0, scale: 1.0},
+	FamilyAnthropic: {charsPerToken: 3.5, runesPerToken: 0.9, scale: 1.15},
+	FamilyGemini:    {charsPerToken: 4.2, runesPerToken: 1.3, scale: 0.95},
+	FamilyMistral:   {charsPerToken: 3.6, runesPerToken: 0.9, scale: 1.1},
+	FamilyLlama:     {charsPerToken: 3.9, runesPerToken: 1.0, scale: 1.05},
+	FamilyGeneric:   {charsPerToken: 3.8, runesPerToken: 1.0, scale: 1.1},
+}
+
+// estimates the token count of text for a family without a vocabulary
+func Estimate(text string, family Family) int {
+	if text == "" {
+		return 0
+	}
+	cal, ok := calibrations[family]
+	if !ok {
+		cal = calibrations[FamilyGeneric]
+	}
+
+	var tokens float64
+	for _, piece := range splitCl100k(text) {
+		r, _ := utf8.DecodeRuneInString(strings.TrimLeft(piece, " "))
+		switch {
+		case isNumber(r), isSpace(r):
+			tokens++
+		case isLetter(r):
+			latin, other := 0, 0
+			for _, c := range piece {
+				if c < utf8.RuneSelf {
+					latin++
+				} else {
+					other++
+				}
+			}
+			t := float64(other) / cal.runesPerToken
+			if latin > 0 {
+				// short words are almost always a single token
+				if latin <= 7 {
+					t++
+				} else {
+					t += math.Ceil(float64(latin) / cal.charsPerToken)
+				}
+			}
+			tokens += t
+		default:
+			tokens += math.Ceil(float64(utf8.RuneCountInString(piece)) / 2)
+		}
+	}
+	return int(math.Ceil(tokens * cal.scale))
+}
+
+// counts tokens in text for a model, exact when the model's encoding is available
+func CountText(text, model string) int {
+	if name := EncodingForModel(model); name != "" {
+		if enc, err := GetEncoding(name); err == nil {
+			return enc.Count(text)
+		}
+	}
+	return Estimate(text, FamilyForModel(model))
+}
+
+// per family overhead of the chat format
+type overhead struct {
+	perMessage int // role markers and separators
+	perReply   int // priming of the assistant reply
+	perTools   int // fixed cost of enabling tool use
+	perTool    int // per tool definition
+}
+
+var overheads = map[Family]overhead{
+	FamilyOpenAI:    {perMessage: 3, perReply: 3, perTools: 12, perTool: 8},
+	FamilyAnthropic: {perMessage: 4, perReply: 3, perTools: 346, perTool: 10},
+	FamilyGemini:    {perMessage: 4, perReply: 0, perTools: 10, perTool: 6},
+	FamilyMistral:   {perMessage: 4, perReply: 2, perTools: 20, perTool: 8},
+	FamilyLlama:     {perMessage: 5, perReply: 4, perTools: 30, perTool: 8},
+	FamilyGeneric:   {perMessage: 4, perReply: 3, perTools: 20, perTool: 8},
+}
+
+// counts prompt tokens for messages and tool definitions sent to model
+func CountTokens(messages []sdk.Message, tools map[string]sdk.Tool, model string) int {
+	oh := overheads[FamilyForModel(model)]
+
+	total := oh.perReply
+	for _, m := range messages {
+		total += oh.perMessage
+		total += CountText(m.Role, model)
+		total += CountText(m.Content, model)
+		for _, tc := range m.ToolCalls {
+			total += CountText(tc.Name, model)
+			total += CountText(string(tc.Arguments), model)
+		}
+	}
+
+	if len(tools) > 0 {
+		total += oh.perTools
+		for _, name := range sortedToolNames(tools) {
+			total += oh.perTool
+			total += CountText(renderTool(name, tools[name]), model)
+		}
+	}
+	return total
+}
+
+// returns a counter for model, suitable for the token based history strategies
+func Counter(model string) sdk.TokenCountFunc {
+	return func(messages []sdk.Message) int {
+		return CountTokens(messages, nil, model)
+	}
+}
+
+func sortedToolNames(tools map[string]sdk.Tool) []string {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renders a tool definition roughly the way providers present it to the model
+func renderTool(name string, tool sdk.Tool) string {
+	var b strings.Builder
+	if tool.Description != "" {
+		fmt.Fprintf(&b, "// %s\n", tool.Description)
+	}
+	fmt.Fprintf(&b, "type %s = (_: {\n", name)
+
+	props := make([]string, 0, len(tool.InputSchema))
+	for prop := range tool.InputSchema {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	for _, prop := range props {
+		def := tool.InputSchema[prop]
+		if def.Description != "" {
+			fmt.Fprintf(&b, "// %s\n", def.Description)
+		}
+		optional := "?"
+		if def.Required {
+			optional = ""
+		}
+		fmt.Fprintf(&b, "%s%s: %s,\n", prop, optional, def.Type)
+	}
+	b.WriteString("}) => any;\n")
+	return b.String()
+}
@@ -0,0 +1,170 @@
+// OpenTelemetry spans for SDK events, following the gen_ai semantic conventions
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+const instrumentationName = "github.com/xerohard/ai/v2/tracing"
+
+// gen_ai attribute keys, and custom ai.* keys for what the conventions do not cover
+const (
+	AttrOperationName      = attribute.Key("gen_ai.operation.name")
+	AttrProviderName       = attribute.Key("gen_ai.provider.name")
+	AttrRequestModel       = attribute.Key("gen_ai.request.model")
+	AttrRequestMaxTokens   = attribute.Key("gen_ai.request.max_tokens")
+	AttrRequestTemperature = attribute.Key("gen_ai.request.temperature")
+	AttrResponseModel      = attribute.Key("gen_ai.response.model")
+	AttrFinishReasons      = attribute.Key("gen_ai.response.finish_reasons")
+	AttrInputTokens        = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens       = attribute.Key("gen_ai.usage.output_tokens")
+	AttrToolName           = attribute.Key("gen_ai.tool.name")
+	AttrToolCallID         = attribute.Key("gen_ai.tool.call.id")
+	AttrErrorType          = attribute.Key("error.type")
+	AttrTimeToFirstToken   = attribute.Key("ai.response.time_to_first_token") // seconds, the conventions only define it as a server metric
+	AttrResponseBytes      = attribute.Key("ai.response.bytes")
+)
+
+// maps SDK provider names to gen_ai.provider.name values
+var providerNames = map[string]string{
+	"openai":     "openai",
+	"anthropic":  "anthropic",
+	"gemini":     "gcp.gemini",
+	"groq":       "groq",
+	"mistral":    "mistral_ai",
+	"perplexity": "perplexity",
+	"xai":        "x_ai",
+}
+
+// sdk.Observer creating a client span per provider call and an internal span per tool execution
+type Observer struct {
+	tracer trace.Tracer
+}
+
+// creates an observer from a tracer provider, nil uses the global one
+func NewObserver(tp trace.TracerProvider) *Observer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Observer{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (o *Observer) OnRequestStart(ctx context.Context, info *sdk.RequestInfo) context.Context {
+	attrs := []attribute.KeyValue{
+		AttrOperationName.String("chat"),
+		AttrRequestModel.String(info.Model),
+	}
+	if info.Provider != "" {
+		attrs = append(attrs, AttrProviderName.String(providerName(info.Provider)))
+	}
+	if info.MaxTokens > 0 {
+		attrs = append(attrs, AttrRequestMaxTokens.Int(info.MaxTokens))
+	}
+	if info.Temperature != 0 {
+		attrs = append(attrs, AttrRequestTemperature.Float64(float64(info.Temperature)))
+	}
+
+	ctx, _ = o.tracer.Start(ctx, "chat "+info.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(info.Start),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+func (o *Observer) OnRequestEnd(ctx context.Context, info *sdk.RequestInfo, result *sdk.RequestResult) {
+	span := trace.SpanFromContext(ctx)
+
+	if result.Provider != "" {
+		span.SetAttributes(AttrProviderName.String(providerName(result.Provider)))
+	}
+	if result.Model != "" {
+		span.SetAttributes(AttrResponseModel.String(result.Model))
+	}
+	if result.FinishReason != "" {
+		span.SetAttributes(AttrFinishReasons.StringSlice([]string{result.FinishReason}))
+	}
+	if result.Usage.InputTokens > 0 || result.Usage.OutputTokens > 0 {
+		span.SetAttributes(
+			AttrInputTokens.Int(result.Usage.InputTokens),
+			AttrOutputTokens.Int(result.Usage.OutputTokens),
+		)
+	}
+	if result.TimeToFirstToken > 0 {
+		span.SetAttributes(AttrTimeToFirstToken.Float64(result.TimeToFirstToken.Seconds()))
+	}
+	recordError(span, result.Err)
+	span.End(trace.WithTimestamp(info.Start.Add(result.Duration)))
+}
+
+func (o *Observer) OnStreamFirstToken(ctx context.Context, info *sdk.RequestInfo) {
+	trace.SpanFromContext(ctx).AddEvent("ai.first_token")
+}
+
+func (o *Observer) OnStreamEnd(ctx context.Context, info *sdk.RequestInfo, result *sdk.RequestResult) {
+	trace.SpanFromContext(ctx).AddEvent("ai.stream_end", trace.WithAttributes(
+		AttrResponseBytes.Int(result.Bytes),
+	))
+}
+
+func (o *Observer) OnToolStart(ctx context.Context, call sdk.ToolCallRequest) context.Context {
+	ctx, _ = o.tracer.Start(ctx, "execute_tool "+call.Name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			AttrOperationName.String("execute_tool"),
+			AttrToolName.String(call.Name),
+			AttrToolCallID.String(call.ID),
+		),
+	)
+	return ctx
+}
+
+func (o *Observer) OnToolEnd(ctx context.Context, call sdk.ToolCallRequest, err error) {
+	span := trace.SpanFromContext(ctx)
+	recordError(span, err)
+	span.End()
+}
+
+func providerName(name string) string {
+	if mapped, ok := providerNames[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(AttrErrorType.String(errorType(err)))
+}
+
+// error.type value, the status code for API errors
+func errorType(err error) string {
+	switch sdk.ClassifyError(err) {
+	case sdk.ErrorServer, sdk.ErrorRateLimit, sdk.ErrorAuth, sdk.ErrorClient:
+		var apiErr *sdk.APIError
+		if errors.As(err, &apiErr) {
+			return strconv.Itoa(apiErr.StatusCode)
+		}
+	case sdk.ErrorTimeout:
+		return "timeout"
+	case sdk.ErrorNetwork:
+		return "network"
+	case sdk.ErrorContentFilter:
+		return "content_filter"
+	}
+	return "_OTHER"
+}
@@ -0,0 +1,229 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xerohard/ai/v2/base"
+	"github.com/xerohard/ai/v2/sdk"
+)
+
+// replays canned OpenAI style bodies, one per call
+type fakeCaller struct {
+	bodies []string
+	status int
+}
+
+func (f *fakeCaller) Name() string { return "openai" }
+
+func (f *fakeCaller) CallAPI(ctx context.Context, messages []sdk.Message, stream bool, opts *sdk.Options) (io.ReadCloser, error) {
+	if f.status != 0 {
+		return nil, &sdk.APIError{StatusCode: f.status, Message: "failed"}
+	}
+	body := f.bodies[0]
+	f.bodies = f.bodies[1:]
+	return io.NopCloser(strings.NewReader(body)), nil
+}
+
+func (f *fakeCaller) ParseResponse(body io.Reader, onChunk func(string) error) error {
+	return base.ParseJsonStream(body, onChunk)
+}
+
+func (f *fakeCaller) ParseEvents(body io.Reader, onEvent func(sdk.StreamEvent) error) error {
+	return base.ParseJsonEvents(body, onEvent)
+}
+
+// base.Provider hides the caller's Name, the SDK reads it from the outer provider
+type namedProvider struct {
+	*base.Provider
+}
+
+func (namedProvider) Name() string { return "openai" }
+
+func newClient(t *testing.T, caller *fakeCaller) (*sdk.SDK, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	provider := namedProvider{&base.Provider{APICaller: caller}}
+	return sdk.NewSDK(provider, sdk.WithObserver(NewObserver(tp))), exporter
+}
+
+const completionBody = `{"model":"gpt-4o-2024-08-06","choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`
+
+const streamBody = `data: {"choices":[{"delta":{"content":"hel"}}]}
+
+data: {"choices":[{"delta":{"content":"lo"}}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}
+
+data: [DONE]
+`
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestCompletionSpan(t *testing.T) {
+	client, exporter := newClient(t, &fakeCaller{bodies: []string{completionBody}})
+
+	resp := client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Model:     "gpt-4o",
+		MaxTokens: 100,
+		Messages:  []sdk.Message{{Role: "user", Content: "hello"}},
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "chat gpt-4o" || span.SpanKind != trace.SpanKindClient {
+		t.Errorf("span = %q kind %v", span.Name, span.SpanKind)
+	}
+
+	a := attrs(span)
+	if a[AttrOperationName].AsString() != "chat" || a[AttrProviderName].AsString() != "openai" {
+		t.Errorf("operation and provider = %v, %v", a[AttrOperationName], a[AttrProviderName])
+	}
+	if a[AttrRequestModel].AsString() != "gpt-4o" || a[AttrResponseModel].AsString() != "gpt-4o-2024-08-06" {
+		t.Errorf("models = %v, %v", a[AttrRequestModel], a[AttrResponseModel])
+	}
+	if a[AttrRequestMaxTokens].AsInt64() != 100 {
+		t.Errorf("max tokens = %v", a[AttrRequestMaxTokens])
+	}
+	if a[AttrInputTokens].AsInt64() != 12 || a[AttrOutputTokens].AsInt64() != 3 {
+		t.Errorf("usage = %v, %v", a[AttrInputTokens], a[AttrOutputTokens])
+	}
+	if got := a[AttrFinishReasons].AsStringSlice(); len(got) != 1 || got[0] != "stop" {
+		t.Errorf("finish reasons = %v", got)
+	}
+	if span.Status.Code == codes.Error {
+		t.Errorf("unexpected error status %v", span.Status)
+	}
+}
+
+func TestStreamSpan(t *testing.T) {
+	client, exporter := newClient(t, &fakeCaller{bodies: []string{streamBody}})
+
+	resp := client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Model:    "gpt-4o",
+		Stream:   true,
+		Messages: []sdk.Message{{Role: "user", Content: "hello"}},
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	text, err := io.ReadAll(resp.Stream)
+	if err != nil || string(text) != "hello" {
+		t.Fatalf("stream = %q, %v", text, err)
+	}
+	resp.Stream.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+
+	a := attrs(span)
+	if a[AttrInputTokens].AsInt64() != 7 || a[AttrOutputTokens].AsInt64() != 2 {
+		t.Errorf("stream usage = %v, %v", a[AttrInputTokens], a[AttrOutputTokens])
+	}
+	if _, ok := a[AttrTimeToFirstToken]; !ok {
+		t.Error("missing time to first token")
+	}
+
+	var events []string
+	for _, e := range span.Events {
+		events = append(events, e.Name)
+	}
+	if strings.Join(events, ",") != "ai.first_token,ai.stream_end" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestErrorSpan(t *testing.T) {
+	client, exporter := newClient(t, &fakeCaller{status: 429})
+
+	resp := client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []sdk.Message{{Role: "user", Content: "hello"}},
+	})
+	if resp.Error == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := exporter.GetSpans()[0]
+	if span.Status.Code != codes.Error {
+		t.Errorf("status = %v", span.Status)
+	}
+	if got := attrs(span)[AttrErrorType].AsString(); got != "429" {
+		t.Errorf("error.type = %q", got)
+	}
+	if len(span.Events) == 0 || span.Events[0].Name != "exception" {
+		t.Errorf("error not recorded: %v", span.Events)
+	}
+}
+
+func TestToolSpan(t *testing.T) {
+	toolCall, _ := json.Marshal(map[string]interface{}{
+		"model": "gpt-4o",
+		"choices": []interface{}{map[string]interface{}{
+			"finish_reason": "tool_calls",
+			"message": map[string]interface{}{
+				"role":       "assistant",
+				"tool_calls": []interface{}{map[string]string{"id": "call-1", "name": "lookup", "arguments": "{}"}},
+			},
+		}},
+	})
+	client, exporter := newClient(t, &fakeCaller{bodies: []string{string(toolCall), completionBody}})
+
+	resp := client.ChatCompletion(context.Background(), &sdk.CompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []sdk.Message{{Role: "user", Content: "look it up"}},
+		Tools: map[string]sdk.Tool{
+			"lookup": {Execute: func(ctx context.Context, args json.RawMessage) (any, error) { return "found", nil }},
+		},
+	})
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+
+	var tool *tracetest.SpanStub
+	chats := 0
+	for _, span := range exporter.GetSpans() {
+		switch {
+		case span.Name == "execute_tool lookup":
+			tool = &span
+		case strings.HasPrefix(span.Name, "chat "):
+			chats++
+		}
+	}
+	if chats != 2 {
+		t.Errorf("got %d chat spans, want 2", chats)
+	}
+	if tool == nil {
+		t.Fatal("missing tool span")
+	}
+	a := attrs(*tool)
+	if tool.SpanKind != trace.SpanKindInternal || a[AttrToolName].AsString() != "lookup" || a[AttrToolCallID].AsString() != "call-1" {
+		t.Errorf("tool span = %v %v", tool.SpanKind, a)
+	}
+}